@@ -2,17 +2,28 @@ package config
 
 // Handler contains handler configuration
 type Handler struct {
-	Slack        Slack
-	SlackWebhook SlackWebhook
-	Hipchat      Hipchat
-	Mattermost   Mattermost
-	Flock        Flock
-	Webhook      Webhook
-	CloudEvent   CloudEvent
-	MSTeams      MSTeams
-	SMTP         SMTP
-	Lark         Lark
-	Discord      Discord
+	Slack         Slack
+	SlackWebhook  SlackWebhook
+	Hipchat       Hipchat
+	Mattermost    Mattermost
+	Flock         Flock
+	Webhook       Webhook
+	CloudEvent    CloudEvent
+	MSTeams       MSTeams
+	SMTP          SMTP
+	Lark          Lark
+	Discord       Discord
+	PagerDuty     PagerDuty
+	Opsgenie      Opsgenie
+	Alertmanager  Alertmanager
+	Kafka         Kafka
+	Nats          Nats
+	Elasticsearch Elasticsearch
+	Loki          Loki
+	Syslog        Syslog
+	Splunk        Splunk
+	File          File
+	S3            S3
 }
 
 // Resource contains resource configuration
@@ -37,10 +48,30 @@ type Resource struct {
 	HPA                   ResourceConfig
 	Event                 ResourceConfig
 	CoreEvent             ResourceConfig
+
+	CronJob                        ResourceConfig
+	PersistentVolumeClaim          ResourceConfig
+	NetworkPolicy                  ResourceConfig
+	Role                           ResourceConfig
+	RoleBinding                    ResourceConfig
+	PodDisruptionBudget            ResourceConfig
+	ResourceQuota                  ResourceConfig
+	LimitRange                     ResourceConfig
+	Endpoints                      ResourceConfig
+	EndpointSlice                  ResourceConfig
+	StorageClass                   ResourceConfig
+	PriorityClass                  ResourceConfig
+	MutatingWebhookConfiguration   ResourceConfig
+	ValidatingWebhookConfiguration ResourceConfig
+	CustomResourceDefinition       ResourceConfig
 }
 
 // Config struct contains statemonitor configuration
 type Config struct {
+	// Cluster is the name of the watched cluster, identifying its events in the handlers receiving the events of several clusters,
+	// KW_CLUSTER by default
+	Cluster string
+
 	// Handlers know how to send notifications to specific services.
 	Handler Handler
 
@@ -53,6 +84,100 @@ type Config struct {
 	Message Message
 	// Diff properties .
 	Diff Diff
+	// Routes send events to a subset of the handlers
+	Routes []Route
+	// SeverityRules classify the events, the first matching rule sets the severity
+	SeverityRules []SeverityRule
+	// Rollup groups the events of owned objects into one notification on their owner
+	Rollup Rollup
+	// Rollout follows the rollouts of deployments, statefulsets and daemonsets after their spec updates
+	Rollout Rollout
+	// PodHealth detects the failures of the pods
+	PodHealth PodHealth
+	// NodeHealth detects the changes of the nodes health and configuration
+	NodeHealth NodeHealth
+	// EventPipeline notifies the core events of the CoreEvent and Event resources instead of their diffs
+	EventPipeline EventPipeline
+}
+
+// EventPipeline forwards the core events of the watched CoreEvent and Event resources as events of their involved object.
+// The first occurrence of an event is notified right away, its repeats within the window as one summary
+type EventPipeline struct {
+	Enabled bool
+	// Types of the forwarded events, Warning by default
+	Types []string
+	// Reasons of the forwarded events, e.g. FailedScheduling, empty for all
+	Reasons []string
+	// InvolvedKinds are the kinds of the objects the forwarded events are about, e.g. Pod, empty for all
+	InvolvedKinds []string
+	// WindowSeconds the repeats of an event are aggregated within, 300 by default
+	WindowSeconds int
+}
+
+// NodeHealth notifies the transitions of the Ready, MemoryPressure, DiskPressure, PIDPressure and NetworkUnavailable
+// conditions of the nodes, their reboots, kubelet version changes, cordons and taints
+type NodeHealth struct {
+	Enabled bool
+}
+
+// PodHealth notifies the failures of the pods: containers in CrashLoopBackOff or ImagePullBackOff,
+// terminated OOMKilled or with a non-zero exit code, evicted pods and pods pending for too long
+type PodHealth struct {
+	Enabled bool
+	// PendingSeconds after which a pod still pending is notified, 300 by default
+	PendingSeconds int
+}
+
+// Rollout sends a follow-up event when the rollout started by a spec update completes or fails
+type Rollout struct {
+	Enabled bool
+	// TimeoutSeconds after which a rollout still in progress is notified as failed, 0 to wait for it indefinitely.
+	// Deployments also fail on their progress deadline
+	TimeoutSeconds int
+}
+
+// Rollup groups the events of the objects owned by a workload (Pod -> ReplicaSet -> Deployment, Job -> CronJob)
+// within a window into one notification on the top-level owner listing the affected children
+type Rollup struct {
+	Enabled bool
+	// WindowSeconds the events of an owner and of its children are grouped within, 10 by default
+	WindowSeconds int
+	// DropWatchedChildren drops the events of the objects whose top-level owner is watched,
+	// with or without rollup
+	DropWatchedChildren bool
+}
+
+// SeverityRule sets the severity of the events it matches: info, warning or critical.
+// Empty conditions match everything, events matching no rule are info for creates,
+// warning for updates and critical for deletes
+type SeverityRule struct {
+	// Kinds the rule applies to, e.g. Deployment
+	Kinds []string
+	// EventTypes the rule applies to: add, update, delete or transient
+	EventTypes []string
+	// Namespaces the rule applies to
+	Namespaces []string
+	// Paths patterns matching at least one changed path of an update, same syntax as IgnoreRule.Path
+	Paths []string
+	// Reasons of the core events, pod failures or node changes the rule applies to, e.g. BackOff, OOMKilled or NodeNotReady
+	Reasons []string
+	// Severity of the matching events
+	Severity string
+}
+
+// Route sends the events routed to it to a subset of the handlers.
+// Objects are routed with the kubestatewatch.io/route annotation or by the route filter
+type Route struct {
+	// Name of the route
+	Name string
+	// Handlers the events are sent to, by configuration name (e.g. msteams, slack), empty for all
+	Handlers []string
+	// Filter is a CEL expression selecting the events sent to the route, see ResourceConfig.Filter.
+	// Routes with a filter also receive the events of objects without route annotation matching it
+	Filter string
+	// Severities of the events sent to the route: info, warning or critical, empty for all.
+	// Like Filter, routes with severities also receive the matching events of objects without route annotation
+	Severities []string
 }
 
 type NamespacesConfig struct {
@@ -70,10 +195,45 @@ type ResourceConfig struct {
 	//if empty, all events will be processed
 	IncludeEvenTypes []string
 	IgnorePath       []string
+	// IgnoreRules for this resource only
+	IgnoreRules []IgnoreRule
+	// KeepManagedFields keeps metadata.managedFields of the watched objects, they are dropped by default
+	KeepManagedFields bool
+	// DropStatus removes the status of the watched objects before caching them
+	DropStatus bool
+	// LabelSelector restricts the watched objects by label, e.g. "app.kubernetes.io/managed-by!=Helm"
+	LabelSelector string
+	// FieldSelector restricts the watched objects by field, e.g. "type=Warning" for core Events
+	FieldSelector string
+	// Filter is a CEL expression the events must match to be notified,
+	// evaluated against event, object, oldObject, diff and namespaceLabels
+	Filter string
+	// DebounceSeconds coalesces the events of an object within the window into one notification,
+	// a create followed by a delete becomes a transient event. 0 notifies every event
+	DebounceSeconds int
 }
 type Diff struct {
 	//IgnorePath for all resources
 	IgnorePath []string
+	// IgnoreRules for all resources, can be scoped with Kinds and Namespaces
+	IgnoreRules []IgnoreRule
+}
+
+// IgnoreRule drops the changes matching Path from update diffs
+type IgnoreRule struct {
+	// JSON pointer pattern of the changes to ignore.
+	// "*" matches one segment, "**" any number of segments,
+	// "re:<expr>" matches a key by regular expression and globs
+	// like "kubectl.kubernetes.io~1*" match within a single key
+	Path string
+	// Kinds the rule applies to (e.g. Deployment), empty for all
+	Kinds []string
+	// Namespaces the rule applies to, empty for all
+	Namespaces []string
+	// Value is a regular expression the changed value must match, empty for any value
+	Value string
+	// OnlyChanged ignores the update only if the matching changes are the only ones in the diff
+	OnlyChanged bool
 }
 
 // Message contains message configuration.
@@ -160,6 +320,252 @@ type CloudEvent struct {
 	Url     string
 }
 
+// PagerDuty contains PagerDuty Events API v2 configuration
+type PagerDuty struct {
+	Enabled bool
+	// RoutingKey is the integration key of the PagerDuty service
+	RoutingKey string
+	// Url of the Events API, https://events.pagerduty.com/v2/enqueue by default
+	Url string
+}
+
+// Opsgenie contains Opsgenie Alert API configuration
+type Opsgenie struct {
+	Enabled bool
+	// ApiKey of an API integration
+	ApiKey string
+	// Url of the API, https://api.opsgenie.com by default, https://api.eu.opsgenie.com for the EU accounts
+	Url string
+	// Responders of the alerts
+	Responders []OpsgenieResponder
+	// Routes set the responders of the alerts sent by the routes, instead of Responders
+	Routes []OpsgenieRoute
+	// Tags added to the tags of the alerts
+	Tags []string
+}
+
+// OpsgenieResponder is a team, user, escalation or schedule, identified by its id or name (username for the users)
+type OpsgenieResponder struct {
+	Type     string
+	Id       string
+	Name     string
+	Username string
+}
+
+// OpsgenieRoute sets the responders of the alerts sent by a route, see Route
+type OpsgenieRoute struct {
+	Name       string
+	Responders []OpsgenieResponder
+}
+
+// Alertmanager contains Prometheus Alertmanager configuration
+type Alertmanager struct {
+	Enabled bool
+	// Url of Alertmanager, e.g. http://alertmanager.monitoring:9093
+	Url string
+	// ResolveSeconds after which the alerts of the changes are resolved, 300 by default
+	ResolveSeconds int
+	// ResendSeconds is the interval the alerts of the ongoing conditions are sent again, 60 by default
+	ResendSeconds int
+}
+
+// Kafka contains Kafka producer configuration
+type Kafka struct {
+	Enabled bool
+	// Brokers are the addresses of the seed brokers, host:port
+	Brokers []string
+	// Topic is a Go template of the topic of the events, e.g. "kubestatewatch.{{ .Namespace }}", "kubestatewatch" by default.
+	// The template is executed with the fields of the event and Cluster
+	Topic string
+	// Format of the messages: json (default) or cloudevents for the CloudEvents JSON structured mode
+	Format string
+	SASL   KafkaSASL
+	TLS    TLS
+	// Acks required from the brokers: all (default), leader or none. Idempotent writes require all
+	Acks string
+	// DisableIdempotence disables the idempotent producer, enabled by default
+	DisableIdempotence bool
+	// LingerMs the records wait for a batch, 0 by default
+	LingerMs int
+	// BatchMaxBytes is the maximum size of a batch, 1MB by default
+	BatchMaxBytes int
+	// Compression of the batches: none, gzip, snappy, lz4 or zstd
+	Compression string
+}
+
+// KafkaSASL contains the SASL authentication of the Kafka producer
+type KafkaSASL struct {
+	// Mechanism is PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512, empty to disable SASL
+	Mechanism string
+	Username  string
+	Password  string
+}
+
+// Nats contains NATS publisher configuration
+type Nats struct {
+	Enabled bool
+	// Url of the servers, comma separated, e.g. nats://nats:4222
+	Url string
+	// Subject is a Go template of the subject of the events,
+	// "kubestatewatch.{{ .Cluster }}.{{ .Namespace }}.{{ .Kind }}.{{ .Reason }}" by default.
+	// The template is executed with the fields of the event and Cluster, the empty fields being replaced by "_"
+	Subject string
+	// JetStream waits for the acknowledgement of the stream of the subject, deduplicating the events by Nats-Msg-Id
+	JetStream bool
+	// Authentication with a token, a username and password or a credentials file
+	Token     string
+	Username  string
+	Password  string
+	CredsFile string
+	TLS       TLS
+}
+
+// Elasticsearch contains Elasticsearch and OpenSearch bulk indexing configuration
+type Elasticsearch struct {
+	Enabled bool
+	// Url of the cluster, e.g. https://elasticsearch:9200
+	Url string
+	// Index is a Go template of the index of the events executed with their document,
+	// "kubestatewatch-{{ .Time.Format "2006.01.02" }}" by default
+	Index string
+	// Basic authentication, or the API key, the base64 encoded id:api_key
+	Username string
+	Password string
+	ApiKey   string
+	TLS      TLS
+	// BatchSize is the number of buffered events sending a bulk request, 500 by default
+	BatchSize int
+	// FlushSeconds is the maximum time the events are buffered, 5 by default
+	FlushSeconds int
+	// Retries of the items rejected by a bulk request with a 429 or 5xx status, 3 by default
+	Retries int
+	// InstallTemplate creates or updates the index template of the events at startup
+	InstallTemplate bool
+	// IndexPattern is the index pattern of the index template, "kubestatewatch-*" by default
+	IndexPattern string
+}
+
+// Loki contains Grafana Loki push configuration
+type Loki struct {
+	Enabled bool
+	// Url of Loki, e.g. http://loki-gateway.monitoring
+	Url string
+	// TenantId is sent in the X-Scope-OrgID header of the multi-tenant Loki
+	TenantId string
+	// Basic authentication, e.g. for Grafana Cloud
+	Username string
+	Password string
+	TLS      TLS
+	// Format of the push requests: protobuf (default), snappy compressed, or json
+	Format string
+	// BatchBytes is the size of the buffered log lines sending a push request, 1MB by default
+	BatchBytes int
+	// FlushSeconds is the maximum time the events are buffered, 1 by default
+	FlushSeconds int
+	// Retries of the push requests failing with a 429 or 5xx status, 3 by default
+	Retries int
+}
+
+// Syslog contains RFC 5424 syslog configuration
+type Syslog struct {
+	Enabled bool
+	// Network is udp (default), tcp or tls
+	Network string
+	// Address of the syslog server, host:port
+	Address string
+	// Facility of the messages, local0 by default
+	Facility string
+	// Hostname of the messages, the cluster or the host name by default
+	Hostname string
+	// AppName of the messages, kubestatewatch by default
+	AppName string
+	// StructuredDataId is the SD-ID of the element of the event fields, event@32473 by default
+	StructuredDataId string
+	// MaxUdpBytes truncates the longer UDP messages, which wouldn't fit a datagram, 2048 by default
+	MaxUdpBytes int
+	TLS         TLS
+}
+
+// Splunk contains Splunk HTTP Event Collector configuration
+type Splunk struct {
+	Enabled bool
+	// Url of the HTTP Event Collector, e.g. https://splunk:8088
+	Url   string
+	Token string
+	// Index of the events, the default index of the token when empty
+	Index string
+	// Sourcetype of the events, _json by default
+	Sourcetype string
+	// Source of the events, kubestatewatch by default
+	Source string
+	TLS    TLS
+	// BatchSize is the number of buffered events sending a request, 100 by default
+	BatchSize int
+	// FlushSeconds is the maximum time the events are buffered, 5 by default
+	FlushSeconds int
+	// Retries of the requests failing with a 429 or 5xx status, 3 by default
+	Retries int
+}
+
+// S3 contains S3 compatible archive configuration
+type S3 struct {
+	Enabled bool
+	// Endpoint of the storage, e.g. https://minio.minio:9000, s3.amazonaws.com by default
+	Endpoint string
+	// Region of the bucket, us-east-1 by default
+	Region string
+	Bucket string
+	// Prefix of the objects, followed by cluster/date/hour
+	Prefix string
+	// AccessKey and SecretKey, the AWS environment variables or the IAM role of the pod when empty
+	AccessKey string
+	SecretKey string
+	// PathStyle addresses the bucket in the path of the requests, as MinIO expects, instead of the host
+	PathStyle bool
+	TLS       TLS
+	// Encryption is the server side encryption of the objects: AES256 or aws:kms
+	Encryption string
+	// KmsKeyId is the KMS key of the aws:kms encryption, the default key of the bucket when empty
+	KmsKeyId string
+	// ObjectLockMode is the retention of the objects: GOVERNANCE or COMPLIANCE, the bucket needing object lock enabled
+	ObjectLockMode string
+	// RetentionDays is the retention period of the objects locked by ObjectLockMode
+	RetentionDays int
+	// BatchBytes is the size of the buffered events uploading an object, 5MB by default
+	BatchBytes int
+	// FlushSeconds is the maximum time the events are buffered, 300 by default
+	FlushSeconds int
+	// Retries of the uploads failing with a 429 or 5xx status, 3 by default
+	Retries int
+}
+
+// File contains JSON lines file configuration
+type File struct {
+	Enabled bool
+	// Path of the file, /var/log/kubestatewatch/events.jsonl by default
+	Path string
+	// MaxSizeMB is the size rotating the file, 100 by default
+	MaxSizeMB int
+	// RotateHours is the age rotating the file, 0 to rotate by size only
+	RotateHours int
+	// MaxBackups is the number of rotated files kept, 10 by default
+	MaxBackups int
+	// Compress gzips the rotated files
+	Compress bool
+}
+
+// TLS contains the TLS configuration of the connections of a handler
+type TLS struct {
+	Enabled bool
+	// CaFile is the CA certificate verifying the server, the system CAs by default
+	CaFile string
+	// CertFile and KeyFile are the client certificate and key
+	CertFile string
+	KeyFile  string
+	// InsecureSkipVerify disables the verification of the server certificate
+	InsecureSkipVerify bool
+}
+
 // MSTeams contains MSTeams configuration
 type MSTeams struct {
 	Enabled bool
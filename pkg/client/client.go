@@ -10,15 +10,26 @@ import (
 	"github.com/marvasgit/kubestatewatch/config"
 	"github.com/marvasgit/kubestatewatch/pkg/controller"
 	"github.com/marvasgit/kubestatewatch/pkg/handlers"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers/alertmanager"
 	"github.com/marvasgit/kubestatewatch/pkg/handlers/cloudevent"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers/elasticsearch"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers/filesink"
 	"github.com/marvasgit/kubestatewatch/pkg/handlers/flock"
 	"github.com/marvasgit/kubestatewatch/pkg/handlers/hipchat"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers/kafka"
 	"github.com/marvasgit/kubestatewatch/pkg/handlers/lark"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers/loki"
 	"github.com/marvasgit/kubestatewatch/pkg/handlers/mattermost"
 	"github.com/marvasgit/kubestatewatch/pkg/handlers/msteam"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers/nats"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers/opsgenie"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers/pagerduty"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers/s3"
 	"github.com/marvasgit/kubestatewatch/pkg/handlers/slack"
 	"github.com/marvasgit/kubestatewatch/pkg/handlers/slackwebhook"
 	"github.com/marvasgit/kubestatewatch/pkg/handlers/smtpClient"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers/splunk"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers/syslog"
 	"github.com/marvasgit/kubestatewatch/pkg/handlers/webhook"
 	"github.com/marvasgit/kubestatewatch/pkg/utils"
 	"github.com/sirupsen/logrus"
@@ -51,40 +62,86 @@ func loadConfig() config.Config {
 	if err := k.Unmarshal("", &config); err != nil {
 		logrus.Fatalf("error loading config: %v", err)
 	}
+	// the name of the cluster is shared by the handlers
+	if config.Cluster == "" {
+		config.Cluster = os.Getenv("KW_CLUSTER")
+	}
 	return config
 }
 
-// parseEventHandler returns the respective handler object specified in the config file.
-func parseEventHandler(conf *config.Config) []handlers.Handler {
+// parseEventHandler returns the handler objects enabled in the config file, by the name of their configuration.
+func parseEventHandler(conf *config.Config) map[string]handlers.Handler {
 
-	var eventHandlers []handlers.Handler
-	switch {
-	case conf.Handler.Slack.Enabled && len(conf.Handler.Slack.Channel) > 0 || len(conf.Handler.Slack.Token) > 0:
-		eventHandlers = append(eventHandlers, new(slack.Slack))
-	case conf.Handler.SlackWebhook.Enabled && len(conf.Handler.SlackWebhook.Channel) > 0 || len(conf.Handler.SlackWebhook.Username) > 0 || len(conf.Handler.SlackWebhook.Slackwebhookurl) > 0:
-		eventHandlers = append(eventHandlers, new(slackwebhook.SlackWebhook))
-	case conf.Handler.Hipchat.Enabled && len(conf.Handler.Hipchat.Room) > 0 || len(conf.Handler.Hipchat.Token) > 0:
-		eventHandlers = append(eventHandlers, new(hipchat.Hipchat))
-	case conf.Handler.Mattermost.Enabled && len(conf.Handler.Mattermost.Channel) > 0 || len(conf.Handler.Mattermost.Url) > 0:
-		eventHandlers = append(eventHandlers, new(mattermost.Mattermost))
-	case conf.Handler.Flock.Enabled && len(conf.Handler.Flock.Url) > 0:
-		eventHandlers = append(eventHandlers, new(flock.Flock))
-	case conf.Handler.Webhook.Enabled && len(conf.Handler.Webhook.Url) > 0:
-		eventHandlers = append(eventHandlers, new(webhook.Webhook))
-	case conf.Handler.CloudEvent.Enabled && len(conf.Handler.CloudEvent.Url) > 0:
-		eventHandlers = append(eventHandlers, new(cloudevent.CloudEvent))
-	case conf.Handler.MSTeams.Enabled && len(conf.Handler.MSTeams.WebhookURL) > 0:
-		eventHandlers = append(eventHandlers, new(msteam.MSTeams))
-	case conf.Handler.SMTP.Enabled && len(conf.Handler.SMTP.Smarthost) > 0 || len(conf.Handler.SMTP.To) > 0:
-		eventHandlers = append(eventHandlers, new(smtpClient.SMTP))
-	case conf.Handler.Lark.Enabled && len(conf.Handler.Lark.WebhookURL) > 0:
-		eventHandlers = append(eventHandlers, new(lark.Webhook))
-	default:
-		eventHandlers = append(eventHandlers, new(handlers.Default))
-	}
-	for _, eventHandler := range eventHandlers {
+	eventHandlers := map[string]handlers.Handler{}
+	if conf.Handler.Slack.Enabled && (len(conf.Handler.Slack.Channel) > 0 || len(conf.Handler.Slack.Token) > 0) {
+		eventHandlers["slack"] = new(slack.Slack)
+	}
+	if conf.Handler.SlackWebhook.Enabled && (len(conf.Handler.SlackWebhook.Channel) > 0 || len(conf.Handler.SlackWebhook.Username) > 0 || len(conf.Handler.SlackWebhook.Slackwebhookurl) > 0) {
+		eventHandlers["slackwebhook"] = new(slackwebhook.SlackWebhook)
+	}
+	if conf.Handler.Hipchat.Enabled && (len(conf.Handler.Hipchat.Room) > 0 || len(conf.Handler.Hipchat.Token) > 0) {
+		eventHandlers["hipchat"] = new(hipchat.Hipchat)
+	}
+	if conf.Handler.Mattermost.Enabled && (len(conf.Handler.Mattermost.Channel) > 0 || len(conf.Handler.Mattermost.Url) > 0) {
+		eventHandlers["mattermost"] = new(mattermost.Mattermost)
+	}
+	if conf.Handler.Flock.Enabled && len(conf.Handler.Flock.Url) > 0 {
+		eventHandlers["flock"] = new(flock.Flock)
+	}
+	if conf.Handler.Webhook.Enabled && len(conf.Handler.Webhook.Url) > 0 {
+		eventHandlers["webhook"] = new(webhook.Webhook)
+	}
+	if conf.Handler.CloudEvent.Enabled && len(conf.Handler.CloudEvent.Url) > 0 {
+		eventHandlers["cloudevent"] = new(cloudevent.CloudEvent)
+	}
+	if conf.Handler.MSTeams.Enabled && len(conf.Handler.MSTeams.WebhookURL) > 0 {
+		eventHandlers["msteams"] = new(msteam.MSTeams)
+	}
+	if conf.Handler.SMTP.Enabled && (len(conf.Handler.SMTP.Smarthost) > 0 || len(conf.Handler.SMTP.To) > 0) {
+		eventHandlers["smtp"] = new(smtpClient.SMTP)
+	}
+	if conf.Handler.Lark.Enabled && len(conf.Handler.Lark.WebhookURL) > 0 {
+		eventHandlers["lark"] = new(lark.Webhook)
+	}
+	if conf.Handler.PagerDuty.Enabled {
+		eventHandlers["pagerduty"] = new(pagerduty.PagerDuty)
+	}
+	if conf.Handler.Opsgenie.Enabled {
+		eventHandlers["opsgenie"] = new(opsgenie.Opsgenie)
+	}
+	if conf.Handler.Alertmanager.Enabled {
+		eventHandlers["alertmanager"] = new(alertmanager.Alertmanager)
+	}
+	if conf.Handler.Kafka.Enabled {
+		eventHandlers["kafka"] = new(kafka.Kafka)
+	}
+	if conf.Handler.Nats.Enabled {
+		eventHandlers["nats"] = new(nats.Nats)
+	}
+	if conf.Handler.Elasticsearch.Enabled {
+		eventHandlers["elasticsearch"] = new(elasticsearch.Elasticsearch)
+	}
+	if conf.Handler.Loki.Enabled {
+		eventHandlers["loki"] = new(loki.Loki)
+	}
+	if conf.Handler.Syslog.Enabled {
+		eventHandlers["syslog"] = new(syslog.Syslog)
+	}
+	if conf.Handler.Splunk.Enabled {
+		eventHandlers["splunk"] = new(splunk.Splunk)
+	}
+	if conf.Handler.File.Enabled {
+		eventHandlers["file"] = new(filesink.File)
+	}
+	if conf.Handler.S3.Enabled {
+		eventHandlers["s3"] = new(s3.S3)
+	}
+	if len(eventHandlers) == 0 {
+		eventHandlers["default"] = new(handlers.Default)
+	}
+	for name, eventHandler := range eventHandlers {
 		if err := eventHandler.Init(conf); err != nil {
-			logrus.Fatal(err)
+			logrus.Fatalf("%s: %v", name, err)
 		}
 	}
 	return eventHandlers
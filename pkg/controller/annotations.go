@@ -0,0 +1,159 @@
+package controller
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/marvasgit/kubestatewatch/pkg/filter"
+	"github.com/sirupsen/logrus"
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/metadata/metadatainformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Annotations of the watched objects and of their namespaces overriding the configuration.
+// Namespace annotations apply to every object inside, the object's annotations taking precedence
+const (
+	// AnnotationIgnore set to "true" silences every notification of the object
+	AnnotationIgnore = "kubestatewatch.io/ignore"
+	// AnnotationIgnorePaths is a comma separated list of path patterns ignored in the update diffs
+	AnnotationIgnorePaths = "kubestatewatch.io/ignore-paths"
+	// AnnotationSeverity overrides the status of the notifications: info, warning or critical
+	AnnotationSeverity = "kubestatewatch.io/severity"
+	// AnnotationRoute sends the notifications to the handlers of the named route only
+	AnnotationRoute = "kubestatewatch.io/route"
+	// AnnotationMuteUntil silences the notifications until the given RFC 3339 time
+	AnnotationMuteUntil = "kubestatewatch.io/mute-until"
+)
+
+// namespaceStore caches the namespaces metadata to read their annotations, nil when namespaces can't be watched
+var namespaceStore cache.Store
+
+// overrides holds the settings read from the annotations of an object and of its namespace
+type overrides struct {
+	ignore      bool
+	ignoreRules filter.IgnoreRules
+	status      string
+	route       string
+	muteUntil   time.Time
+}
+
+// muted reports whether the notifications of the object are silenced at the given time
+func (o overrides) muted(now time.Time) bool {
+	return o.ignore || now.Before(o.muteUntil)
+}
+
+// getOverrides reads the overrides from the annotations of the object and of its namespace
+func getOverrides(objectAnnotations map[string]string, namespaceAnnotations map[string]string) overrides {
+	var o overrides
+
+	annotations := map[string]string{}
+	for k, v := range namespaceAnnotations {
+		annotations[k] = v
+	}
+	for k, v := range objectAnnotations {
+		annotations[k] = v
+	}
+
+	if v, ok := annotations[AnnotationIgnore]; ok {
+		o.ignore, _ = strconv.ParseBool(v)
+	}
+
+	// ignored paths of the namespace and of the object are combined
+	var rules []config.IgnoreRule
+	for _, a := range []map[string]string{namespaceAnnotations, objectAnnotations} {
+		for _, path := range strings.Split(a[AnnotationIgnorePaths], ",") {
+			if path = strings.TrimSpace(path); path != "" {
+				rules = append(rules, config.IgnoreRule{Path: path})
+			}
+		}
+	}
+	if len(rules) > 0 {
+		var err error
+		if o.ignoreRules, err = filter.CompileIgnoreRules(rules); err != nil {
+			logrus.Warnf("Invalid %s annotation: %v", AnnotationIgnorePaths, err)
+		}
+	}
+
+	if v, ok := annotations[AnnotationSeverity]; ok {
+		if status, ok := event.StatusFromSeverity(v); ok {
+			o.status = status
+		} else {
+			logrus.Warnf("Invalid %s annotation: %s", AnnotationSeverity, v)
+		}
+	}
+
+	o.route = annotations[AnnotationRoute]
+
+	if v, ok := annotations[AnnotationMuteUntil]; ok {
+		muteUntil, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			logrus.Warnf("Invalid %s annotation: %v", AnnotationMuteUntil, err)
+		}
+		o.muteUntil = muteUntil
+	}
+
+	return o
+}
+
+// objectAnnotations returns the annotations of a watched object, nil if it has no metadata
+func objectAnnotations(obj interface{}) map[string]string {
+	if obj == nil {
+		return nil
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return nil
+	}
+	return accessor.GetAnnotations()
+}
+
+// namespaceMeta returns the metadata of the namespace from the namespace cache
+func namespaceMeta(namespace string) meta_v1.Object {
+	if namespaceStore == nil || namespace == "" {
+		return nil
+	}
+	obj, exists, err := namespaceStore.GetByKey(namespace)
+	if err != nil || !exists {
+		return nil
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return nil
+	}
+	return accessor
+}
+
+// namespaceAnnotations returns the annotations of the namespace from the namespace cache
+func namespaceAnnotations(namespace string) map[string]string {
+	if ns := namespaceMeta(namespace); ns != nil {
+		return ns.GetAnnotations()
+	}
+	return nil
+}
+
+// namespaceLabels returns the labels of the namespace from the namespace cache
+func namespaceLabels(namespace string) map[string]string {
+	if ns := namespaceMeta(namespace); ns != nil {
+		return ns.GetLabels()
+	}
+	return nil
+}
+
+// startNamespaceCache watches the namespaces metadata and waits for the cache to be synced
+func startNamespaceCache(metadataClient metadata.Interface, stopCh <-chan struct{}) cache.Store {
+	informer := metadatainformer.NewFilteredMetadataInformer(metadataClient, api_v1.SchemeGroupVersion.WithResource("namespaces"), "", 0, cache.Indexers{}, nil).Informer()
+	go informer.Run(stopCh)
+
+	if !cache.WaitForCacheSync(stopCh, informer.HasSynced) {
+		logrus.Errorf("timed out waiting for namespaces cache to sync, namespace annotations are ignored")
+		return nil
+	}
+	return informer.GetStore()
+}
@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/marvasgit/kubestatewatch/pkg/filter"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers"
+	"github.com/stretchr/testify/assert"
+	"github.com/wI2L/jsondiff"
+)
+
+func TestGetOverrides(t *testing.T) {
+	now := time.Date(2023, 11, 1, 10, 0, 0, 0, time.UTC)
+
+	var Tests = []struct {
+		name        string
+		object      map[string]string
+		namespace   map[string]string
+		muted       bool
+		status      string
+		route       string
+		ignoredPath string
+	}{
+		{name: "no annotations"},
+		{name: "ignored object", object: map[string]string{AnnotationIgnore: "true"}, muted: true},
+		{name: "ignored namespace", namespace: map[string]string{AnnotationIgnore: "true"}, muted: true},
+		{name: "object overrides namespace", object: map[string]string{AnnotationIgnore: "false"}, namespace: map[string]string{AnnotationIgnore: "true"}},
+		{name: "muted until", object: map[string]string{AnnotationMuteUntil: "2023-11-01T11:00:00Z"}, muted: true},
+		{name: "mute expired", object: map[string]string{AnnotationMuteUntil: "2023-11-01T09:00:00Z"}},
+		{name: "invalid mute", object: map[string]string{AnnotationMuteUntil: "tomorrow"}},
+		{name: "severity", object: map[string]string{AnnotationSeverity: "critical"}, status: "Danger"},
+		{name: "namespace severity", namespace: map[string]string{AnnotationSeverity: "info"}, status: "Normal"},
+		{name: "invalid severity", object: map[string]string{AnnotationSeverity: "loud"}},
+		{name: "route", namespace: map[string]string{AnnotationRoute: "payments"}, route: "payments"},
+		{name: "ignore paths", object: map[string]string{AnnotationIgnorePaths: "/spec/replicas, /metadata/labels/*"}, namespace: map[string]string{AnnotationIgnorePaths: "/data"}, ignoredPath: "/spec/replicas"},
+	}
+
+	for _, tt := range Tests {
+		o := getOverrides(tt.object, tt.namespace)
+		assert.Equal(t, tt.muted, o.muted(now), tt.name)
+		assert.Equal(t, tt.status, o.status, tt.name)
+		assert.Equal(t, tt.route, o.route, tt.name)
+		if tt.ignoredPath != "" {
+			patch := jsondiff.Patch{
+				{Type: jsondiff.OperationReplace, Path: tt.ignoredPath},
+				{Type: jsondiff.OperationReplace, Path: "/metadata/labels/app"},
+				{Type: jsondiff.OperationReplace, Path: "/data/key"},
+				{Type: jsondiff.OperationReplace, Path: "/spec/template"},
+			}
+			assert.Len(t, o.ignoreRules.Apply("Deployment", "default", patch), 1, tt.name)
+		}
+	}
+}
+
+type recordingHandler struct {
+	mu     sync.Mutex
+	events []event.StatemonitorEvent
+}
+
+func (r *recordingHandler) Init(c *config.Config) error {
+	return nil
+}
+
+func (r *recordingHandler) Handle(e event.StatemonitorEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *recordingHandler) received() []event.StatemonitorEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]event.StatemonitorEvent(nil), r.events...)
+}
+
+func TestDispatch(t *testing.T) {
+	msteams, slack := &recordingHandler{}, &recordingHandler{}
+	c := &Controller{eventHandlers: map[string]handlers.Handler{"msteams": msteams, "slack": slack}}
+	var err error
+	routes, err = getRoutes([]config.Route{
+		{Name: "payments", Handlers: []string{"slack", "smtp"}},
+		{Name: "all"},
+	})
+	assert.NoError(t, err)
+	defer func() { routes = nil }()
+
+	e := event.StatemonitorEvent{Name: "foo"}
+	c.dispatch(e, filter.Input{}, "")
+	c.dispatch(e, filter.Input{}, "payments")
+	c.dispatch(e, filter.Input{}, "all")
+	c.dispatch(e, filter.Input{}, "unknown")
+
+	assert.Len(t, msteams.events, 3)
+	assert.Len(t, slack.events, 4)
+	assert.Equal(t, []string{"", "payments", "all", ""}, []string{slack.events[0].Route, slack.events[1].Route, slack.events[2].Route, slack.events[3].Route})
+}
@@ -31,6 +31,7 @@ import (
 
 	"github.com/marvasgit/kubestatewatch/config"
 	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/marvasgit/kubestatewatch/pkg/filter"
 	"github.com/marvasgit/kubestatewatch/pkg/handlers"
 	"github.com/marvasgit/kubestatewatch/pkg/utils"
 	"github.com/prometheus/client_golang/prometheus"
@@ -38,19 +39,28 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/wI2L/jsondiff"
 
+	admissionregistration_v1 "k8s.io/api/admissionregistration/v1"
 	apps_v1 "k8s.io/api/apps/v1"
 	autoscaling_v1 "k8s.io/api/autoscaling/v1"
 	batch_v1 "k8s.io/api/batch/v1"
 	api_v1 "k8s.io/api/core/v1"
+	discovery_v1 "k8s.io/api/discovery/v1"
 	events_v1 "k8s.io/api/events/v1"
 	networking_v1 "k8s.io/api/networking/v1"
+	policy_v1 "k8s.io/api/policy/v1"
 	rbac_v1 "k8s.io/api/rbac/v1"
+	scheduling_v1 "k8s.io/api/scheduling/v1"
+	storage_v1 "k8s.io/api/storage/v1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
@@ -65,6 +75,15 @@ const BATCH_V1 = "batch/v1"
 const RBAC_V1 = "rbac.authorization.k8s.io/v1"
 const NETWORKING_V1 = "networking.k8s.io/v1"
 const EVENTS_V1 = "events.k8s.io/v1"
+const POLICY_V1 = "policy/v1"
+const DISCOVERY_V1 = "discovery.k8s.io/v1"
+const STORAGE_V1 = "storage.k8s.io/v1"
+const SCHEDULING_V1 = "scheduling.k8s.io/v1"
+const ADMISSIONREGISTRATION_V1 = "admissionregistration.k8s.io/v1"
+const APIEXTENSIONS_V1 = "apiextensions.k8s.io/v1"
+
+// drainTimeout bounds the wait for the queued events on termination
+const drainTimeout = 10 * time.Second
 
 var serverStartTime time.Time
 var confDiff config.Diff
@@ -96,9 +115,19 @@ type Controller struct {
 	clientset     kubernetes.Interface
 	queue         workqueue.RateLimitingInterface
 	informer      cache.SharedIndexInformer
-	eventHandlers []handlers.Handler
+	eventHandlers map[string]handlers.Handler
+	ignoreRules   filter.IgnoreRules
+	filter        *filter.Expression
+	// debouncer holds the events of the objects within the debounce window, nil without debounce
+	debouncer *debouncer
 }
 
+// controllers are the created controllers, drained on termination
+var (
+	controllersMu sync.Mutex
+	controllers   []*Controller
+)
+
 func objName(obj interface{}) string {
 	return reflect.TypeOf(obj).Name()
 }
@@ -112,38 +141,278 @@ func init() {
 
 // TODO: we don't need the informer to be indexed
 // Start prepares watchers and run their controllers, then waits for process termination signals
-func Start(conf *config.Config, eventHandlers []handlers.Handler, list *utils.TTLList) {
+func Start(conf *config.Config, eventHandlers map[string]handlers.Handler, list *utils.TTLList) {
 	ttlList = list
 	//TODO remove imput of evenhandlers and decide here
 	var kubeClient kubernetes.Interface
+	var metadataClient metadata.Interface
+	var dynamicClient dynamic.Interface
 
 	if _, err := rest.InClusterConfig(); err != nil {
 		kubeClient = utils.GetClientOutOfCluster()
+		metadataClient = utils.GetMetadataClientOutOfCluster()
+		dynamicClient = utils.GetDynamicClientOutOfCluster()
 	} else {
 		kubeClient = utils.GetClient()
+		metadataClient = utils.GetMetadataClient()
+		dynamicClient = utils.GetDynamicClient()
 	}
 
 	confDiff = conf.Diff
+	var err error
+	if routes, err = getRoutes(conf.Routes); err != nil {
+		logrus.Fatalf("error loading routes: %v", err)
+	}
+	if severityRules, err = filter.CompileSeverityRules(conf.SeverityRules); err != nil {
+		logrus.Fatalf("error loading severity rules: %v", err)
+	}
+	ownerClient = metadataClient
+	dropWatchedChildren = conf.Rollup.DropWatchedChildren
+	if conf.Rollup.Enabled {
+		rollups = newRollup(conf.Rollup)
+	}
+	if conf.Rollout.Enabled {
+		rollouts = newRolloutTracker(conf.Rollout)
+	}
+	if conf.PodHealth.Enabled {
+		podHealth = newPodAnalyzer(conf.PodHealth)
+	}
+	nodeHealthEnabled = conf.NodeHealth.Enabled
+	if conf.EventPipeline.Enabled {
+		eventPipeline = newEventAggregator(conf.EventPipeline)
+	}
+	skipClusterResources(conf)
 	namespaces = getNamespaces(kubeClient, &conf.NamespacesConfig)
 	stopCh := make(chan struct{})
-	ns := ""
 	defer close(stopCh)
 
+	// namespace annotations can only be read with cluster-wide access to the namespaces
+	if len(conf.NamespacesConfig.Include) == 0 {
+		namespaceStore = startNamespaceCache(metadataClient, stopCh)
+	}
+
+	for _, ns := range watchNamespaces(&conf.NamespacesConfig) {
+		startNamespacedControllers(conf, kubeClient, metadataClient, eventHandlers, ns, stopCh)
+	}
+
+	if conf.Resource.Namespace.Enabled {
+		informer := newInformer(metadataClient, api_v1.SchemeGroupVersion.WithResource("namespaces"), "", conf.Resource.Namespace,
+			&cache.ListWatch{
+				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+					return kubeClient.CoreV1().Namespaces().List(context.Background(), options)
+				},
+				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+					return kubeClient.CoreV1().Namespaces().Watch(context.Background(), options)
+				},
+			},
+			&api_v1.Namespace{},
+		)
+
+		c := newResourceController(kubeClient, eventHandlers, informer, objName(api_v1.Namespace{}), V1, conf.Resource.Namespace)
+
+		go c.Run(stopCh)
+	}
+
+	if conf.Resource.Node.Enabled {
+		informer := newInformer(metadataClient, api_v1.SchemeGroupVersion.WithResource("nodes"), "", conf.Resource.Node,
+			&cache.ListWatch{
+				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+					return kubeClient.CoreV1().Nodes().List(context.Background(), options)
+				},
+				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+					return kubeClient.CoreV1().Nodes().Watch(context.Background(), options)
+				},
+			},
+			&api_v1.Node{},
+		)
+
+		c := newResourceController(kubeClient, eventHandlers, informer, objName(api_v1.Node{}), V1, conf.Resource.Node)
+
+		go c.Run(stopCh)
+	}
+
+	if conf.Resource.ClusterRole.Enabled {
+		informer := newInformer(metadataClient, rbac_v1.SchemeGroupVersion.WithResource("clusterroles"), "", conf.Resource.ClusterRole,
+			&cache.ListWatch{
+				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+					return kubeClient.RbacV1().ClusterRoles().List(context.Background(), options)
+				},
+				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+					return kubeClient.RbacV1().ClusterRoles().Watch(context.Background(), options)
+				},
+			},
+			&rbac_v1.ClusterRole{},
+		)
+
+		c := newResourceController(kubeClient, eventHandlers, informer, objName(rbac_v1.ClusterRole{}), RBAC_V1, conf.Resource.ClusterRole)
+
+		go c.Run(stopCh)
+	}
+
+	if conf.Resource.ClusterRoleBinding.Enabled {
+		informer := newInformer(metadataClient, rbac_v1.SchemeGroupVersion.WithResource("clusterrolebindings"), "", conf.Resource.ClusterRoleBinding,
+			&cache.ListWatch{
+				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+					return kubeClient.RbacV1().ClusterRoleBindings().List(context.Background(), options)
+				},
+				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+					return kubeClient.RbacV1().ClusterRoleBindings().Watch(context.Background(), options)
+				},
+			},
+			&rbac_v1.ClusterRoleBinding{},
+		)
+
+		c := newResourceController(kubeClient, eventHandlers, informer, objName(rbac_v1.ClusterRoleBinding{}), RBAC_V1, conf.Resource.ClusterRoleBinding)
+
+		go c.Run(stopCh)
+	}
+
+	if conf.Resource.PersistentVolume.Enabled {
+		informer := newInformer(metadataClient, api_v1.SchemeGroupVersion.WithResource("persistentvolumes"), "", conf.Resource.PersistentVolume,
+			&cache.ListWatch{
+				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+					return kubeClient.CoreV1().PersistentVolumes().List(context.Background(), options)
+				},
+				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+					return kubeClient.CoreV1().PersistentVolumes().Watch(context.Background(), options)
+				},
+			},
+			&api_v1.PersistentVolume{},
+		)
+
+		c := newResourceController(kubeClient, eventHandlers, informer, objName(api_v1.PersistentVolume{}), V1, conf.Resource.PersistentVolume)
+
+		go c.Run(stopCh)
+	}
+
+	if conf.Resource.StorageClass.Enabled {
+		informer := newInformer(metadataClient, storage_v1.SchemeGroupVersion.WithResource("storageclasses"), "", conf.Resource.StorageClass,
+			&cache.ListWatch{
+				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+					return kubeClient.StorageV1().StorageClasses().List(context.Background(), options)
+				},
+				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+					return kubeClient.StorageV1().StorageClasses().Watch(context.Background(), options)
+				},
+			},
+			&storage_v1.StorageClass{},
+		)
+
+		c := newResourceController(kubeClient, eventHandlers, informer, objName(storage_v1.StorageClass{}), STORAGE_V1, conf.Resource.StorageClass)
+
+		go c.Run(stopCh)
+	}
+
+	if conf.Resource.PriorityClass.Enabled {
+		informer := newInformer(metadataClient, scheduling_v1.SchemeGroupVersion.WithResource("priorityclasses"), "", conf.Resource.PriorityClass,
+			&cache.ListWatch{
+				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+					return kubeClient.SchedulingV1().PriorityClasses().List(context.Background(), options)
+				},
+				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+					return kubeClient.SchedulingV1().PriorityClasses().Watch(context.Background(), options)
+				},
+			},
+			&scheduling_v1.PriorityClass{},
+		)
+
+		c := newResourceController(kubeClient, eventHandlers, informer, objName(scheduling_v1.PriorityClass{}), SCHEDULING_V1, conf.Resource.PriorityClass)
+
+		go c.Run(stopCh)
+	}
+
+	if conf.Resource.MutatingWebhookConfiguration.Enabled {
+		informer := newInformer(metadataClient, admissionregistration_v1.SchemeGroupVersion.WithResource("mutatingwebhookconfigurations"), "", conf.Resource.MutatingWebhookConfiguration,
+			&cache.ListWatch{
+				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+					return kubeClient.AdmissionregistrationV1().MutatingWebhookConfigurations().List(context.Background(), options)
+				},
+				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+					return kubeClient.AdmissionregistrationV1().MutatingWebhookConfigurations().Watch(context.Background(), options)
+				},
+			},
+			&admissionregistration_v1.MutatingWebhookConfiguration{},
+		)
+
+		c := newResourceController(kubeClient, eventHandlers, informer, objName(admissionregistration_v1.MutatingWebhookConfiguration{}), ADMISSIONREGISTRATION_V1, conf.Resource.MutatingWebhookConfiguration)
+
+		go c.Run(stopCh)
+	}
+
+	if conf.Resource.ValidatingWebhookConfiguration.Enabled {
+		informer := newInformer(metadataClient, admissionregistration_v1.SchemeGroupVersion.WithResource("validatingwebhookconfigurations"), "", conf.Resource.ValidatingWebhookConfiguration,
+			&cache.ListWatch{
+				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+					return kubeClient.AdmissionregistrationV1().ValidatingWebhookConfigurations().List(context.Background(), options)
+				},
+				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+					return kubeClient.AdmissionregistrationV1().ValidatingWebhookConfigurations().Watch(context.Background(), options)
+				},
+			},
+			&admissionregistration_v1.ValidatingWebhookConfiguration{},
+		)
+
+		c := newResourceController(kubeClient, eventHandlers, informer, objName(admissionregistration_v1.ValidatingWebhookConfiguration{}), ADMISSIONREGISTRATION_V1, conf.Resource.ValidatingWebhookConfiguration)
+
+		go c.Run(stopCh)
+	}
+
+	// CustomResourceDefinitions aren't served by the typed clientset, they are watched as unstructured objects
+	if conf.Resource.CustomResourceDefinition.Enabled {
+		gvr := schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+		informer := newInformer(metadataClient, gvr, "", conf.Resource.CustomResourceDefinition,
+			&cache.ListWatch{
+				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+					return dynamicClient.Resource(gvr).List(context.Background(), options)
+				},
+				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+					return dynamicClient.Resource(gvr).Watch(context.Background(), options)
+				},
+			},
+			&unstructured.Unstructured{},
+		)
+
+		c := newResourceController(kubeClient, eventHandlers, informer, "CustomResourceDefinition", APIEXTENSIONS_V1, conf.Resource.CustomResourceDefinition)
+
+		go c.Run(stopCh)
+	}
+
+	sigterm := make(chan os.Signal, 1)
+	signal.Notify(sigterm, syscall.SIGTERM)
+	signal.Notify(sigterm, syscall.SIGINT)
+	<-sigterm
+
+	logrus.Info("Processing the queued events")
+	drainControllers()
+	if rollups != nil {
+		logrus.Info("Sending the pending rollups")
+		rollups.flushAll()
+	}
+	for name, eventHandler := range eventHandlers {
+		if stopper, ok := eventHandler.(handlers.Stopper); ok {
+			logrus.Infof("Stopping %s", name)
+			stopper.Stop()
+		} else if flusher, ok := eventHandler.(handlers.Flusher); ok {
+			logrus.Infof("Flushing the events buffered by %s", name)
+			flusher.Flush()
+		}
+	}
+}
+
+// startNamespacedControllers starts the controllers of the namespaced resources watching the namespace ns, "" for all namespaces
+func startNamespacedControllers(conf *config.Config, kubeClient kubernetes.Interface, metadataClient metadata.Interface, eventHandlers map[string]handlers.Handler, ns string, stopCh <-chan struct{}) {
+	// events are always watched with typed informers (no metadata client) as their content is not in the metadata
 	if conf.Resource.CoreEvent.Enabled {
-		allCoreEventsInformer := cache.NewSharedIndexInformer(
+		allCoreEventsInformer := newInformer(nil, api_v1.SchemeGroupVersion.WithResource("events"), ns, conf.Resource.CoreEvent,
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					options.FieldSelector = ""
 					return kubeClient.CoreV1().Events(ns).List(context.Background(), options)
 				},
 				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					options.FieldSelector = ""
 					return kubeClient.CoreV1().Events(ns).Watch(context.Background(), options)
 				},
 			},
 			&api_v1.Event{},
-			0, //Skip resync
-			cache.Indexers{},
 		)
 
 		c := newResourceController(kubeClient, eventHandlers, allCoreEventsInformer, objName(api_v1.Event{}), V1, conf.Resource.CoreEvent)
@@ -152,21 +421,16 @@ func Start(conf *config.Config, eventHandlers []handlers.Handler, list *utils.TT
 	}
 
 	if conf.Resource.Event.Enabled {
-
-		allEventsInformer := cache.NewSharedIndexInformer(
+		allEventsInformer := newInformer(nil, events_v1.SchemeGroupVersion.WithResource("events"), ns, conf.Resource.Event,
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					options.FieldSelector = ""
 					return kubeClient.EventsV1().Events(ns).List(context.Background(), options)
 				},
 				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					options.FieldSelector = ""
 					return kubeClient.EventsV1().Events(ns).Watch(context.Background(), options)
 				},
 			},
 			&events_v1.Event{},
-			0, //Skip resync
-			cache.Indexers{},
 		)
 
 		c := newResourceController(kubeClient, eventHandlers, allEventsInformer, objName(events_v1.Event{}), EVENTS_V1, conf.Resource.Event)
@@ -176,7 +440,7 @@ func Start(conf *config.Config, eventHandlers []handlers.Handler, list *utils.TT
 
 	if conf.Resource.Pod.Enabled {
 		pods := kubeClient.CoreV1().Pods(ns)
-		informer := cache.NewSharedIndexInformer(
+		informer := newInformer(metadataClient, api_v1.SchemeGroupVersion.WithResource("pods"), ns, conf.Resource.Pod,
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
 					ll, err := pods.List(context.Background(), options)
@@ -188,8 +452,6 @@ func Start(conf *config.Config, eventHandlers []handlers.Handler, list *utils.TT
 				},
 			},
 			&api_v1.Pod{},
-			0, //Skip resync
-			cache.Indexers{},
 		)
 
 		c := newResourceController(kubeClient, eventHandlers, informer, objName(api_v1.Pod{}), V1, conf.Resource.Pod)
@@ -198,7 +460,7 @@ func Start(conf *config.Config, eventHandlers []handlers.Handler, list *utils.TT
 	}
 
 	if conf.Resource.HPA.Enabled {
-		informer := cache.NewSharedIndexInformer(
+		informer := newInformer(metadataClient, autoscaling_v1.SchemeGroupVersion.WithResource("horizontalpodautoscalers"), ns, conf.Resource.HPA,
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
 					return kubeClient.AutoscalingV1().HorizontalPodAutoscalers(ns).List(context.Background(), options)
@@ -208,8 +470,6 @@ func Start(conf *config.Config, eventHandlers []handlers.Handler, list *utils.TT
 				},
 			},
 			&autoscaling_v1.HorizontalPodAutoscaler{},
-			0, //Skip resync
-			cache.Indexers{},
 		)
 
 		c := newResourceController(kubeClient, eventHandlers, informer, objName(autoscaling_v1.HorizontalPodAutoscaler{}), AUTOSCALING_V1, conf.Resource.HPA)
@@ -219,7 +479,7 @@ func Start(conf *config.Config, eventHandlers []handlers.Handler, list *utils.TT
 	}
 
 	if conf.Resource.DaemonSet.Enabled {
-		informer := cache.NewSharedIndexInformer(
+		informer := newInformer(metadataClient, apps_v1.SchemeGroupVersion.WithResource("daemonsets"), ns, conf.Resource.DaemonSet,
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
 					return kubeClient.AppsV1().DaemonSets(ns).List(context.Background(), options)
@@ -229,8 +489,6 @@ func Start(conf *config.Config, eventHandlers []handlers.Handler, list *utils.TT
 				},
 			},
 			&apps_v1.DaemonSet{},
-			0, //Skip resync
-			cache.Indexers{},
 		)
 
 		c := newResourceController(kubeClient, eventHandlers, informer, objName(apps_v1.DaemonSet{}), APPS_V1, conf.Resource.DaemonSet)
@@ -239,7 +497,7 @@ func Start(conf *config.Config, eventHandlers []handlers.Handler, list *utils.TT
 	}
 
 	if conf.Resource.StatefulSet.Enabled {
-		informer := cache.NewSharedIndexInformer(
+		informer := newInformer(metadataClient, apps_v1.SchemeGroupVersion.WithResource("statefulsets"), ns, conf.Resource.StatefulSet,
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
 					return kubeClient.AppsV1().StatefulSets(ns).List(context.Background(), options)
@@ -249,8 +507,6 @@ func Start(conf *config.Config, eventHandlers []handlers.Handler, list *utils.TT
 				},
 			},
 			&apps_v1.StatefulSet{},
-			0, //Skip resync
-			cache.Indexers{},
 		)
 
 		c := newResourceController(kubeClient, eventHandlers, informer, objName(apps_v1.StatefulSet{}), APPS_V1, conf.Resource.StatefulSet)
@@ -258,7 +514,7 @@ func Start(conf *config.Config, eventHandlers []handlers.Handler, list *utils.TT
 	}
 
 	if conf.Resource.ReplicaSet.Enabled {
-		informer := cache.NewSharedIndexInformer(
+		informer := newInformer(metadataClient, apps_v1.SchemeGroupVersion.WithResource("replicasets"), ns, conf.Resource.ReplicaSet,
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
 					return kubeClient.AppsV1().ReplicaSets(ns).List(context.Background(), options)
@@ -268,8 +524,6 @@ func Start(conf *config.Config, eventHandlers []handlers.Handler, list *utils.TT
 				},
 			},
 			&apps_v1.ReplicaSet{},
-			0, //Skip resync
-			cache.Indexers{},
 		)
 
 		c := newResourceController(kubeClient, eventHandlers, informer, objName(apps_v1.ReplicaSet{}), APPS_V1, conf.Resource.ReplicaSet)
@@ -278,7 +532,7 @@ func Start(conf *config.Config, eventHandlers []handlers.Handler, list *utils.TT
 	}
 
 	if conf.Resource.Services.Enabled {
-		informer := cache.NewSharedIndexInformer(
+		informer := newInformer(metadataClient, api_v1.SchemeGroupVersion.WithResource("services"), ns, conf.Resource.Services,
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
 					return kubeClient.CoreV1().Services(ns).List(context.Background(), options)
@@ -288,8 +542,6 @@ func Start(conf *config.Config, eventHandlers []handlers.Handler, list *utils.TT
 				},
 			},
 			&api_v1.Service{},
-			0, //Skip resync
-			cache.Indexers{},
 		)
 
 		c := newResourceController(kubeClient, eventHandlers, informer, objName(api_v1.Service{}), V1, conf.Resource.Services)
@@ -298,7 +550,7 @@ func Start(conf *config.Config, eventHandlers []handlers.Handler, list *utils.TT
 	}
 
 	if conf.Resource.Deployment.Enabled {
-		informer := cache.NewSharedIndexInformer(
+		informer := newInformer(metadataClient, apps_v1.SchemeGroupVersion.WithResource("deployments"), ns, conf.Resource.Deployment,
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
 					return kubeClient.AppsV1().Deployments(ns).List(context.Background(), options)
@@ -308,8 +560,6 @@ func Start(conf *config.Config, eventHandlers []handlers.Handler, list *utils.TT
 				},
 			},
 			&apps_v1.Deployment{},
-			0, //Skip resync
-			cache.Indexers{},
 		)
 
 		c := newResourceController(kubeClient, eventHandlers, informer, objName(apps_v1.Deployment{}), APPS_V1, conf.Resource.Deployment)
@@ -317,239 +567,326 @@ func Start(conf *config.Config, eventHandlers []handlers.Handler, list *utils.TT
 		go c.Run(stopCh)
 	}
 
-	if conf.Resource.Namespace.Enabled {
-		informer := cache.NewSharedIndexInformer(
+	if conf.Resource.ReplicationController.Enabled {
+		informer := newInformer(metadataClient, api_v1.SchemeGroupVersion.WithResource("replicationcontrollers"), ns, conf.Resource.ReplicationController,
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().Namespaces().List(context.Background(), options)
+					return kubeClient.CoreV1().ReplicationControllers(ns).List(context.Background(), options)
 				},
 				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().Namespaces().Watch(context.Background(), options)
+					return kubeClient.CoreV1().ReplicationControllers(ns).Watch(context.Background(), options)
 				},
 			},
-			&api_v1.Namespace{},
-			0, //Skip resync
-			cache.Indexers{},
+			&api_v1.ReplicationController{},
 		)
 
-		c := newResourceController(kubeClient, eventHandlers, informer, objName(api_v1.Namespace{}), V1, conf.Resource.Namespace)
+		c := newResourceController(kubeClient, eventHandlers, informer, objName(api_v1.ReplicationController{}), V1, conf.Resource.ReplicationController)
 
 		go c.Run(stopCh)
 	}
 
-	if conf.Resource.ReplicationController.Enabled {
-		informer := cache.NewSharedIndexInformer(
+	if conf.Resource.Job.Enabled {
+		informer := newInformer(metadataClient, batch_v1.SchemeGroupVersion.WithResource("jobs"), ns, conf.Resource.Job,
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().ReplicationControllers(ns).List(context.Background(), options)
+					return kubeClient.BatchV1().Jobs(ns).List(context.Background(), options)
 				},
 				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().ReplicationControllers(ns).Watch(context.Background(), options)
+					return kubeClient.BatchV1().Jobs(ns).Watch(context.Background(), options)
 				},
 			},
-			&api_v1.ReplicationController{},
-			0, //Skip resync
-			cache.Indexers{},
+			&batch_v1.Job{},
 		)
 
-		c := newResourceController(kubeClient, eventHandlers, informer, objName(api_v1.ReplicationController{}), V1, conf.Resource.ReplicationController)
+		c := newResourceController(kubeClient, eventHandlers, informer, objName(batch_v1.Job{}), BATCH_V1, conf.Resource.Job)
 
 		go c.Run(stopCh)
 	}
 
-	if conf.Resource.Job.Enabled {
-		informer := cache.NewSharedIndexInformer(
+	if conf.Resource.ServiceAccount.Enabled {
+		informer := newInformer(metadataClient, api_v1.SchemeGroupVersion.WithResource("serviceaccounts"), ns, conf.Resource.ServiceAccount,
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.BatchV1().Jobs(ns).List(context.Background(), options)
+					return kubeClient.CoreV1().ServiceAccounts(ns).List(context.Background(), options)
 				},
 				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.BatchV1().Jobs(ns).Watch(context.Background(), options)
+					return kubeClient.CoreV1().ServiceAccounts(ns).Watch(context.Background(), options)
 				},
 			},
-			&batch_v1.Job{},
-			0, //Skip resync
-			cache.Indexers{},
+			&api_v1.ServiceAccount{},
 		)
 
-		c := newResourceController(kubeClient, eventHandlers, informer, objName(batch_v1.Job{}), BATCH_V1, conf.Resource.Job)
+		c := newResourceController(kubeClient, eventHandlers, informer, objName(api_v1.ServiceAccount{}), V1, conf.Resource.ServiceAccount)
 
 		go c.Run(stopCh)
 	}
 
-	if conf.Resource.Node.Enabled {
-		informer := cache.NewSharedIndexInformer(
+	if conf.Resource.Secret.Enabled {
+		informer := newInformer(metadataClient, api_v1.SchemeGroupVersion.WithResource("secrets"), ns, conf.Resource.Secret,
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().Nodes().List(context.Background(), options)
+					return kubeClient.CoreV1().Secrets(ns).List(context.Background(), options)
 				},
 				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().Nodes().Watch(context.Background(), options)
+					return kubeClient.CoreV1().Secrets(ns).Watch(context.Background(), options)
 				},
 			},
-			&api_v1.Node{},
-			0, //Skip resync
-			cache.Indexers{},
+			&api_v1.Secret{},
 		)
 
-		c := newResourceController(kubeClient, eventHandlers, informer, objName(api_v1.Node{}), V1, conf.Resource.Node)
+		c := newResourceController(kubeClient, eventHandlers, informer, objName(api_v1.Secret{}), V1, conf.Resource.Secret)
 
 		go c.Run(stopCh)
 	}
 
-	if conf.Resource.ServiceAccount.Enabled {
-		informer := cache.NewSharedIndexInformer(
+	if conf.Resource.ConfigMap.Enabled {
+		informer := newInformer(metadataClient, api_v1.SchemeGroupVersion.WithResource("configmaps"), ns, conf.Resource.ConfigMap,
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().ServiceAccounts(ns).List(context.Background(), options)
+					return kubeClient.CoreV1().ConfigMaps(ns).List(context.Background(), options)
 				},
 				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().ServiceAccounts(ns).Watch(context.Background(), options)
+					return kubeClient.CoreV1().ConfigMaps(ns).Watch(context.Background(), options)
 				},
 			},
-			&api_v1.ServiceAccount{},
-			0, //Skip resync
-			cache.Indexers{},
+			&api_v1.ConfigMap{},
 		)
 
-		c := newResourceController(kubeClient, eventHandlers, informer, objName(api_v1.ServiceAccount{}), V1, conf.Resource.ServiceAccount)
+		c := newResourceController(kubeClient, eventHandlers, informer, objName(api_v1.ConfigMap{}), V1, conf.Resource.ConfigMap)
 
 		go c.Run(stopCh)
 	}
 
-	if conf.Resource.ClusterRole.Enabled {
-		informer := cache.NewSharedIndexInformer(
+	if conf.Resource.Ingress.Enabled {
+		informer := newInformer(metadataClient, networking_v1.SchemeGroupVersion.WithResource("ingresses"), ns, conf.Resource.Ingress,
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.RbacV1().ClusterRoles().List(context.Background(), options)
+					return kubeClient.NetworkingV1().Ingresses(ns).List(context.Background(), options)
 				},
 				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.RbacV1().ClusterRoles().Watch(context.Background(), options)
+					return kubeClient.NetworkingV1().Ingresses(ns).Watch(context.Background(), options)
 				},
 			},
-			&rbac_v1.ClusterRole{},
-			0, //Skip resync
-			cache.Indexers{},
+			&networking_v1.Ingress{},
 		)
 
-		c := newResourceController(kubeClient, eventHandlers, informer, objName(rbac_v1.ClusterRole{}), RBAC_V1, conf.Resource.ClusterRole)
+		c := newResourceController(kubeClient, eventHandlers, informer, objName(networking_v1.Ingress{}), NETWORKING_V1, conf.Resource.Ingress)
 
 		go c.Run(stopCh)
 	}
 
-	if conf.Resource.ClusterRoleBinding.Enabled {
-		informer := cache.NewSharedIndexInformer(
+	if conf.Resource.CronJob.Enabled {
+		informer := newInformer(metadataClient, batch_v1.SchemeGroupVersion.WithResource("cronjobs"), ns, conf.Resource.CronJob,
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.RbacV1().ClusterRoleBindings().List(context.Background(), options)
+					return kubeClient.BatchV1().CronJobs(ns).List(context.Background(), options)
 				},
 				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.RbacV1().ClusterRoleBindings().Watch(context.Background(), options)
+					return kubeClient.BatchV1().CronJobs(ns).Watch(context.Background(), options)
 				},
 			},
-			&rbac_v1.ClusterRoleBinding{},
-			0, //Skip resync
-			cache.Indexers{},
+			&batch_v1.CronJob{},
 		)
 
-		c := newResourceController(kubeClient, eventHandlers, informer, objName(rbac_v1.ClusterRoleBinding{}), RBAC_V1, conf.Resource.ClusterRoleBinding)
+		c := newResourceController(kubeClient, eventHandlers, informer, objName(batch_v1.CronJob{}), BATCH_V1, conf.Resource.CronJob)
 
 		go c.Run(stopCh)
 	}
 
-	if conf.Resource.PersistentVolume.Enabled {
-		informer := cache.NewSharedIndexInformer(
+	if conf.Resource.PersistentVolumeClaim.Enabled {
+		informer := newInformer(metadataClient, api_v1.SchemeGroupVersion.WithResource("persistentvolumeclaims"), ns, conf.Resource.PersistentVolumeClaim,
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().PersistentVolumes().List(context.Background(), options)
+					return kubeClient.CoreV1().PersistentVolumeClaims(ns).List(context.Background(), options)
 				},
 				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().PersistentVolumes().Watch(context.Background(), options)
+					return kubeClient.CoreV1().PersistentVolumeClaims(ns).Watch(context.Background(), options)
 				},
 			},
-			&api_v1.PersistentVolume{},
-			0, //Skip resync
-			cache.Indexers{},
+			&api_v1.PersistentVolumeClaim{},
 		)
 
-		c := newResourceController(kubeClient, eventHandlers, informer, objName(api_v1.PersistentVolume{}), V1, conf.Resource.PersistentVolume)
+		c := newResourceController(kubeClient, eventHandlers, informer, objName(api_v1.PersistentVolumeClaim{}), V1, conf.Resource.PersistentVolumeClaim)
 
 		go c.Run(stopCh)
 	}
 
-	if conf.Resource.Secret.Enabled {
-		informer := cache.NewSharedIndexInformer(
+	if conf.Resource.NetworkPolicy.Enabled {
+		informer := newInformer(metadataClient, networking_v1.SchemeGroupVersion.WithResource("networkpolicies"), ns, conf.Resource.NetworkPolicy,
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().Secrets(ns).List(context.Background(), options)
+					return kubeClient.NetworkingV1().NetworkPolicies(ns).List(context.Background(), options)
 				},
 				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().Secrets(ns).Watch(context.Background(), options)
+					return kubeClient.NetworkingV1().NetworkPolicies(ns).Watch(context.Background(), options)
 				},
 			},
-			&api_v1.Secret{},
-			0, //Skip resync
-			cache.Indexers{},
+			&networking_v1.NetworkPolicy{},
 		)
 
-		c := newResourceController(kubeClient, eventHandlers, informer, objName(api_v1.Secret{}), V1, conf.Resource.Secret)
+		c := newResourceController(kubeClient, eventHandlers, informer, objName(networking_v1.NetworkPolicy{}), NETWORKING_V1, conf.Resource.NetworkPolicy)
 
 		go c.Run(stopCh)
 	}
 
-	if conf.Resource.ConfigMap.Enabled {
-		informer := cache.NewSharedIndexInformer(
+	if conf.Resource.Role.Enabled {
+		informer := newInformer(metadataClient, rbac_v1.SchemeGroupVersion.WithResource("roles"), ns, conf.Resource.Role,
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().ConfigMaps(ns).List(context.Background(), options)
+					return kubeClient.RbacV1().Roles(ns).List(context.Background(), options)
 				},
 				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().ConfigMaps(ns).Watch(context.Background(), options)
+					return kubeClient.RbacV1().Roles(ns).Watch(context.Background(), options)
 				},
 			},
-			&api_v1.ConfigMap{},
-			0, //Skip resync
-			cache.Indexers{},
+			&rbac_v1.Role{},
 		)
 
-		c := newResourceController(kubeClient, eventHandlers, informer, objName(api_v1.ConfigMap{}), V1, conf.Resource.ConfigMap)
+		c := newResourceController(kubeClient, eventHandlers, informer, objName(rbac_v1.Role{}), RBAC_V1, conf.Resource.Role)
 
 		go c.Run(stopCh)
 	}
 
-	if conf.Resource.Ingress.Enabled {
-		informer := cache.NewSharedIndexInformer(
+	if conf.Resource.RoleBinding.Enabled {
+		informer := newInformer(metadataClient, rbac_v1.SchemeGroupVersion.WithResource("rolebindings"), ns, conf.Resource.RoleBinding,
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.NetworkingV1().Ingresses(ns).List(context.Background(), options)
+					return kubeClient.RbacV1().RoleBindings(ns).List(context.Background(), options)
 				},
 				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.NetworkingV1().Ingresses(ns).Watch(context.Background(), options)
+					return kubeClient.RbacV1().RoleBindings(ns).Watch(context.Background(), options)
 				},
 			},
-			&networking_v1.Ingress{},
-			0, //Skip resync
-			cache.Indexers{},
+			&rbac_v1.RoleBinding{},
 		)
 
-		c := newResourceController(kubeClient, eventHandlers, informer, objName(networking_v1.Ingress{}), NETWORKING_V1, conf.Resource.Ingress)
+		c := newResourceController(kubeClient, eventHandlers, informer, objName(rbac_v1.RoleBinding{}), RBAC_V1, conf.Resource.RoleBinding)
+
+		go c.Run(stopCh)
+	}
+
+	if conf.Resource.PodDisruptionBudget.Enabled {
+		informer := newInformer(metadataClient, policy_v1.SchemeGroupVersion.WithResource("poddisruptionbudgets"), ns, conf.Resource.PodDisruptionBudget,
+			&cache.ListWatch{
+				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+					return kubeClient.PolicyV1().PodDisruptionBudgets(ns).List(context.Background(), options)
+				},
+				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+					return kubeClient.PolicyV1().PodDisruptionBudgets(ns).Watch(context.Background(), options)
+				},
+			},
+			&policy_v1.PodDisruptionBudget{},
+		)
+
+		c := newResourceController(kubeClient, eventHandlers, informer, objName(policy_v1.PodDisruptionBudget{}), POLICY_V1, conf.Resource.PodDisruptionBudget)
+
+		go c.Run(stopCh)
+	}
+
+	if conf.Resource.ResourceQuota.Enabled {
+		informer := newInformer(metadataClient, api_v1.SchemeGroupVersion.WithResource("resourcequotas"), ns, conf.Resource.ResourceQuota,
+			&cache.ListWatch{
+				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+					return kubeClient.CoreV1().ResourceQuotas(ns).List(context.Background(), options)
+				},
+				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+					return kubeClient.CoreV1().ResourceQuotas(ns).Watch(context.Background(), options)
+				},
+			},
+			&api_v1.ResourceQuota{},
+		)
+
+		c := newResourceController(kubeClient, eventHandlers, informer, objName(api_v1.ResourceQuota{}), V1, conf.Resource.ResourceQuota)
+
+		go c.Run(stopCh)
+	}
+
+	if conf.Resource.LimitRange.Enabled {
+		informer := newInformer(metadataClient, api_v1.SchemeGroupVersion.WithResource("limitranges"), ns, conf.Resource.LimitRange,
+			&cache.ListWatch{
+				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+					return kubeClient.CoreV1().LimitRanges(ns).List(context.Background(), options)
+				},
+				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+					return kubeClient.CoreV1().LimitRanges(ns).Watch(context.Background(), options)
+				},
+			},
+			&api_v1.LimitRange{},
+		)
+
+		c := newResourceController(kubeClient, eventHandlers, informer, objName(api_v1.LimitRange{}), V1, conf.Resource.LimitRange)
+
+		go c.Run(stopCh)
+	}
+
+	if conf.Resource.Endpoints.Enabled {
+		informer := newInformer(metadataClient, api_v1.SchemeGroupVersion.WithResource("endpoints"), ns, conf.Resource.Endpoints,
+			&cache.ListWatch{
+				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+					return kubeClient.CoreV1().Endpoints(ns).List(context.Background(), options)
+				},
+				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+					return kubeClient.CoreV1().Endpoints(ns).Watch(context.Background(), options)
+				},
+			},
+			&api_v1.Endpoints{},
+		)
+
+		c := newResourceController(kubeClient, eventHandlers, informer, objName(api_v1.Endpoints{}), V1, conf.Resource.Endpoints)
+
+		go c.Run(stopCh)
+	}
+
+	if conf.Resource.EndpointSlice.Enabled {
+		informer := newInformer(metadataClient, discovery_v1.SchemeGroupVersion.WithResource("endpointslices"), ns, conf.Resource.EndpointSlice,
+			&cache.ListWatch{
+				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+					return kubeClient.DiscoveryV1().EndpointSlices(ns).List(context.Background(), options)
+				},
+				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+					return kubeClient.DiscoveryV1().EndpointSlices(ns).Watch(context.Background(), options)
+				},
+			},
+			&discovery_v1.EndpointSlice{},
+		)
+
+		c := newResourceController(kubeClient, eventHandlers, informer, objName(discovery_v1.EndpointSlice{}), DISCOVERY_V1, conf.Resource.EndpointSlice)
 
 		go c.Run(stopCh)
 	}
-	sigterm := make(chan os.Signal, 1)
-	signal.Notify(sigterm, syscall.SIGTERM)
-	signal.Notify(sigterm, syscall.SIGINT)
-	<-sigterm
 }
 
 // TODO: proper implementation of this function without the hack of multi ns
-func newResourceController(client kubernetes.Interface, eventHandlers []handlers.Handler, informer cache.SharedIndexInformer, resourceType string, apiVersion string, resourceConfig config.ResourceConfig) *Controller {
+func newResourceController(client kubernetes.Interface, eventHandlers map[string]handlers.Handler, informer cache.SharedIndexInformer, resourceType string, apiVersion string, resourceConfig config.ResourceConfig) *Controller {
 	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
 	var newEvent Event
 	var eventWrapper EventWrapper
 	eventWrapper.ResourceConfig = &resourceConfig
 
-	var err error
+	ignoreRules, err := filter.CompileIgnoreRules(append(confDiff.IgnoreRules, resourceConfig.IgnoreRules...))
+	if err != nil {
+		logrus.Fatalf("error loading ignore rules for %s: %v", resourceType, err)
+	}
+	var resourceFilter *filter.Expression
+	if resourceConfig.Filter != "" {
+		if resourceFilter, err = filter.CompileExpression(resourceConfig.Filter); err != nil {
+			logrus.Fatalf("error loading filter for %s: %v", resourceType, err)
+		}
+	}
+
+	registerOwnerStore(resourceType, informer.GetStore())
+
+	enqueue := func(ew EventWrapper) { queue.Add(ew) }
+	var debounced *debouncer
+	if resourceConfig.DebounceSeconds > 0 {
+		debounced = newDebouncer(time.Duration(resourceConfig.DebounceSeconds)*time.Second, enqueue)
+		enqueue = debounced.add
+	}
+
+	if err := informer.SetTransform(newTransform(resourceConfig)); err != nil {
+		logrus.Errorf("error setting transform for %s: %v", resourceType, err)
+	}
+
 	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			if resourceConfig.Enabled && (len(resourceConfig.IncludeEvenTypes) == 0 || slices.Contains(resourceConfig.IncludeEvenTypes, "add")) {
@@ -568,7 +905,7 @@ func newResourceController(client kubernetes.Interface, eventHandlers []handlers
 					return
 				}
 
-				if !slices.Contains(namespaces, strings.Split(newEvent.key, "/")[0]) {
+				if !watchedKey(resourceType, newEvent.key) {
 					logrus.Debugf("Skipping adding (namespaceconfig.ignore contains it) %v for %s", resourceType, newEvent.key)
 					return
 				}
@@ -576,7 +913,7 @@ func newResourceController(client kubernetes.Interface, eventHandlers []handlers
 				logrus.WithField("pkg", "statemonitor-"+resourceType).Infof("Processing add to %v: %s", resourceType, newEvent.key)
 
 				eventWrapper.Event = newEvent
-				queue.Add(eventWrapper)
+				enqueue(eventWrapper)
 			} else {
 				logrus.Debugf("Skipping ADD (resource not enabled) %v for %s and is enabled - %t", resourceType, newEvent.key, resourceConfig.Enabled)
 			}
@@ -603,7 +940,7 @@ func newResourceController(client kubernetes.Interface, eventHandlers []handlers
 					return
 				}
 
-				if !slices.Contains(namespaces, strings.Split(newEvent.key, "/")[0]) {
+				if !watchedKey(resourceType, newEvent.key) {
 					logrus.Debugf("Skipping updating(namespaceconfig.ignore contains it) %v for %s", resourceType, newEvent.key)
 					return
 				}
@@ -611,12 +948,14 @@ func newResourceController(client kubernetes.Interface, eventHandlers []handlers
 				logrus.WithField("pkg", "statemonitor-"+resourceType).Infof("Processing update to %v: %s", resourceType, newEvent.key)
 
 				eventWrapper.Event = newEvent
-				queue.Add(eventWrapper)
+				enqueue(eventWrapper)
 			} else {
 				logrus.Debugf("Skipping UPDATE (resource not enabled) %v for %s and is enabled - %t", resourceType, newEvent.key, resourceConfig.Enabled)
 			}
 		},
 		DeleteFunc: func(obj interface{}) {
+			// the failures of a pod are forgotten whatever the event types notified
+			forgetDeletedPod(obj)
 			if resourceConfig.Enabled && (len(resourceConfig.IncludeEvenTypes) == 0 || slices.Contains(resourceConfig.IncludeEvenTypes, "delete")) {
 				var ok bool
 				newEvent.namespace = "" // namespace retrived in processItem incase namespace value is empty
@@ -634,26 +973,62 @@ func newResourceController(client kubernetes.Interface, eventHandlers []handlers
 					return
 				}
 
-				if !slices.Contains(namespaces, strings.Split(newEvent.key, "/")[0]) {
+				if !watchedKey(resourceType, newEvent.key) {
 					logrus.Debugf("Skipping deletion (namespaceconfig.ignore contains it) %v for %s", resourceType, newEvent.key)
 					return
 				}
 
 				logrus.WithField("pkg", "statemonitor-"+resourceType).Infof("Processing delete to %v: %s", resourceType, newEvent.key)
 				eventWrapper.Event = newEvent
-				queue.Add(eventWrapper)
+				enqueue(eventWrapper)
 			} else {
 				logrus.Debugf("Skipping deletion (resource not enabled) %v for %s and is enabled - %t", resourceType, newEvent.key, resourceConfig.Enabled)
 			}
 		},
 	})
 
-	return &Controller{
+	c := &Controller{
 		logger:        logrus.WithField("pkg", resourceType+"-statemonitor"),
 		clientset:     client,
 		informer:      informer,
 		queue:         queue,
 		eventHandlers: eventHandlers,
+		ignoreRules:   ignoreRules,
+		filter:        resourceFilter,
+		debouncer:     debounced,
+	}
+	controllersMu.Lock()
+	controllers = append(controllers, c)
+	controllersMu.Unlock()
+	return c
+}
+
+// drainControllers queues the debounced events and waits for the workers to process the queued events, up to drainTimeout
+func drainControllers() {
+	controllersMu.Lock()
+	created := controllers
+	controllersMu.Unlock()
+
+	for _, c := range created {
+		if c.debouncer != nil {
+			c.debouncer.flushAll()
+		}
+	}
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		for _, c := range created {
+			// the drain of the queue only waits for the events being processed
+			for c.queue.Len() > 0 {
+				time.Sleep(10 * time.Millisecond)
+			}
+			c.queue.ShutDownWithDrain()
+		}
+	}()
+	select {
+	case <-drained:
+	case <-time.After(drainTimeout):
+		logrus.Warnf("Timed out waiting for the queued events after %s", drainTimeout)
 	}
 }
 
@@ -750,23 +1125,36 @@ func (c *Controller) processItem(eventWrapper EventWrapper) error {
 		logrus.Warnf("Deployment is in process for %v timeleft %v", newEvent.namespace, ttlList.GetTTL(newEvent.namespace).String())
 		return nil
 	}
+	// per object overrides from the annotations of the object and its namespace
+	o := getOverrides(objectAnnotations(newEvent.obj), namespaceAnnotations(newEvent.namespace))
+	if o.muted(time.Now()) {
+		logrus.Debugf("Skipping %v %s (muted by annotation)", newEvent.resourceType, newEvent.key)
+		return nil
+	}
+	// pod failures are detected on every create and update, before the diffs
+	if podHealth != nil && newEvent.resourceType == "Pod" && (newEvent.eventType == "create" || newEvent.eventType == "update") {
+		c.analyzePod(newEvent, o)
+	}
+	// node changes are detected on every update, the heartbeats of the conditions being usually ignored in the diffs
+	if nodeHealthEnabled && newEvent.resourceType == "Node" && newEvent.eventType == "update" {
+		c.analyzeNode(newEvent, o)
+	}
+	// core events are notified by the pipeline instead of their diffs
+	if eventPipeline != nil && newEvent.resourceType == objName(api_v1.Event{}) {
+		if newEvent.eventType == "create" || newEvent.eventType == "update" {
+			c.processK8sEvent(newEvent, o)
+		}
+		return nil
+	}
 	// process events based on its type
 	switch newEvent.eventType {
 	case "create":
 		// compare CreationTimestamp and serverStartTime and alert only on latest events
 		// Could be Replaced by using Delta or DeltaFIFO
 		if objectMeta.CreationTimestamp.Sub(serverStartTime).Seconds() > 0 {
-			switch newEvent.resourceType {
-			case "NodeNotReady":
-				status = "Danger"
-			case "NodeReady":
-				status = "Normal"
-			case "NodeRebooted":
-				status = "Danger"
-			case "Backoff":
-				status = "Danger"
-			default:
-				status = "Normal"
+			status = classify(newEvent, nil)
+			if o.status != "" {
+				status = o.status
 			}
 			kbEvent := event.StatemonitorEvent{
 				Name:       newEvent.key,
@@ -777,19 +1165,26 @@ func (c *Controller) processItem(eventWrapper EventWrapper) error {
 				Reason:     "Created",
 			}
 
-			for _, handler := range c.eventHandlers {
-				handler.Handle(kbEvent)
+			if c.notify(kbEvent, newEvent, o.route) {
+				handleMetric(newEvent)
 			}
-
-			handleMetric(newEvent)
 			return nil
 		}
 	case "update":
-		switch newEvent.resourceType {
-		case "Backoff":
-			status = "Danger"
-		default:
-			status = "Warning"
+		// rollouts are followed on every update, status changes are usually ignored in the diffs
+		if rollouts != nil {
+			rollouts.update(c, newEvent, o.route)
+		}
+		diff, patch := compareObjects(eventWrapper, newEvent.namespace, append(o.ignoreRules, c.ignoreRules...))
+		if diff == "" {
+			logrus.Printf("No diff( or ingored paths) found for %s", newEvent.key)
+			//skipping metrics here as there is no valuable diff
+			return nil
+		}
+
+		status = classify(newEvent, patch)
+		if o.status != "" {
+			status = o.status
 		}
 
 		kbEvent := event.StatemonitorEvent{
@@ -799,41 +1194,61 @@ func (c *Controller) processItem(eventWrapper EventWrapper) error {
 			ApiVersion: newEvent.apiVersion,
 			Status:     status,
 			Reason:     "Updated",
-			Diff:       compareObjects(eventWrapper),
+			Diff:       diff,
 		}
 
-		if kbEvent.Diff == "" {
-			logrus.Printf("No diff( or ingored paths) found for %s", newEvent.key)
-			//skipping metrics here as there is no valuable diff
-			return nil
-		}
-
-		for _, handler := range c.eventHandlers {
-			handler.Handle(kbEvent)
+		if c.notify(kbEvent, newEvent, o.route) {
+			handleMetric(newEvent)
 		}
-		handleMetric(newEvent)
 		return nil
 	case "delete":
+		if rollouts != nil {
+			rollouts.stop(newEvent)
+		}
+		status = classify(newEvent, nil)
+		if o.status != "" {
+			status = o.status
+		}
 		kbEvent := event.StatemonitorEvent{
 			Name:       newEvent.key,
 			Namespace:  newEvent.namespace,
 			Kind:       newEvent.resourceType,
 			ApiVersion: newEvent.apiVersion,
-			Status:     "Danger",
+			Status:     status,
 			Reason:     "Deleted",
 		}
 
-		for _, handler := range c.eventHandlers {
-			handler.Handle(kbEvent)
+		if c.notify(kbEvent, newEvent, o.route) {
+			handleMetric(newEvent)
+		}
+		return nil
+	case "transient":
+		// created and deleted within the debounce window, the object is no longer in the store
+		if utils.GetObjectMetaData(newEvent.obj).CreationTimestamp.Sub(serverStartTime).Seconds() > 0 {
+			status = classify(newEvent, nil)
+			if o.status != "" {
+				status = o.status
+			}
+			kbEvent := event.StatemonitorEvent{
+				Name:       newEvent.key,
+				Namespace:  newEvent.namespace,
+				Kind:       newEvent.resourceType,
+				ApiVersion: newEvent.apiVersion,
+				Status:     status,
+				Reason:     "Transient",
+			}
+
+			if c.notify(kbEvent, newEvent, o.route) {
+				handleMetric(newEvent)
+			}
 		}
-		handleMetric(newEvent)
 		return nil
 	}
 	return nil
 }
 
-// compareObjects compares two objects and returns the diff
-func compareObjects(ew EventWrapper) string {
+// compareObjects compares two objects and returns the diff without the ignored changes, and its patch
+func compareObjects(ew EventWrapper, namespace string, ignoreRules filter.IgnoreRules) (string, jsondiff.Patch) {
 	var patch jsondiff.Patch
 	var err error
 	ignorePath := append(confDiff.IgnorePath, ew.ResourceConfig.IgnorePath...)
@@ -853,14 +1268,15 @@ func compareObjects(ew EventWrapper) string {
 	if err != nil {
 		logrus.Printf("Error in comparing objects %s", err)
 	}
+	patch = ignoreRules.Apply(e.resourceType, namespace, patch)
 	b, err := json.MarshalIndent(patch, "", "    ")
 	if err != nil {
 		logrus.Printf("Error in marshalling patch %s", err)
 	}
 	if b == nil || string(b) == "null" {
-		return ""
+		return "", nil
 	}
-	return string(b)
+	return string(b), patch
 }
 
 func compareConfigMaps(old runtime.Object, new runtime.Object) (jsondiff.Patch, error) {
@@ -892,6 +1308,44 @@ func compareConfigMaps(old runtime.Object, new runtime.Object) (jsondiff.Patch,
 	return jsondiff.CompareJSON([]byte(oldDataStr), []byte(newDataStr))
 }
 
+// watchNamespaces returns the namespaces the informers of namespaced resources are scoped to.
+// With NamespacesConfig.Include set, one informer per namespace is started so only namespaced Roles are needed,
+// otherwise a single cluster-wide informer is used ("")
+func watchNamespaces(namespacesConfig *config.NamespacesConfig) []string {
+	if namespacesConfig != nil && len(namespacesConfig.Include) > 0 {
+		return namespacesConfig.Include
+	}
+	return []string{""}
+}
+
+// skipClusterResources disables the cluster scoped resources when the informers are scoped to the included namespaces.
+// The namespaced Roles don't allow to list them, their informers would never sync
+func skipClusterResources(conf *config.Config) {
+	if len(conf.NamespacesConfig.Include) == 0 {
+		return
+	}
+	for _, resource := range []struct {
+		name   string
+		config *config.ResourceConfig
+	}{
+		{"namespace", &conf.Resource.Namespace},
+		{"node", &conf.Resource.Node},
+		{"clusterrole", &conf.Resource.ClusterRole},
+		{"clusterrolebinding", &conf.Resource.ClusterRoleBinding},
+		{"persistentvolume", &conf.Resource.PersistentVolume},
+		{"storageclass", &conf.Resource.StorageClass},
+		{"priorityclass", &conf.Resource.PriorityClass},
+		{"mutatingwebhookconfiguration", &conf.Resource.MutatingWebhookConfiguration},
+		{"validatingwebhookconfiguration", &conf.Resource.ValidatingWebhookConfiguration},
+		{"customresourcedefinition", &conf.Resource.CustomResourceDefinition},
+	} {
+		if resource.config.Enabled {
+			logrus.Warnf("Skipping the cluster scoped resource %s, it can't be watched with namespaceconfig.include", resource.name)
+			resource.config.Enabled = false
+		}
+	}
+}
+
 // getNamespaces returns the namespaces to watch based on the configiration provided *NamespacesConfig
 func getNamespaces(clientset kubernetes.Interface, namespacesConfig *config.NamespacesConfig) []string {
 
@@ -927,6 +1381,15 @@ func getNamespaces(clientset kubernetes.Interface, namespacesConfig *config.Name
 	return namespaces
 }
 
+// watchedKey reports whether the object of the key is in a watched namespace, cluster scoped objects are always watched
+func watchedKey(resourceType string, key string) bool {
+	namespace, _, namespaced := strings.Cut(key, "/")
+	if !namespaced && resourceType != objName(api_v1.Namespace{}) {
+		return true
+	}
+	return slices.Contains(namespaces, namespace)
+}
+
 func handleMetric(newEvent Event) {
 	mu.Lock()
 	defer mu.Unlock()
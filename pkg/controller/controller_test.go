@@ -0,0 +1,27 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSkipClusterResources(t *testing.T) {
+	conf := &config.Config{}
+	conf.Resource.Node.Enabled = true
+	conf.Resource.CustomResourceDefinition.Enabled = true
+	conf.Resource.Pod.Enabled = true
+
+	// watched cluster-wide
+	skipClusterResources(conf)
+	assert.True(t, conf.Resource.Node.Enabled)
+	assert.True(t, conf.Resource.CustomResourceDefinition.Enabled)
+
+	// the cluster scoped resources can't be listed in the included namespaces
+	conf.NamespacesConfig.Include = []string{"shop"}
+	skipClusterResources(conf)
+	assert.False(t, conf.Resource.Node.Enabled)
+	assert.False(t, conf.Resource.CustomResourceDefinition.Enabled)
+	assert.True(t, conf.Resource.Pod.Enabled)
+}
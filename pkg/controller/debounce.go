@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"sync"
+	"time"
+)
+
+// debouncer coalesces the events of an object received within a window into a single event.
+// The window starts with the first event of the object, so a stream of updates can't delay its notification forever
+type debouncer struct {
+	window  time.Duration
+	flush   func(EventWrapper)
+	mu      sync.Mutex
+	pending map[string]*EventWrapper
+	// closed once the pending events were flushed on termination, the next events being flushed right away
+	closed bool
+}
+
+func newDebouncer(window time.Duration, flush func(EventWrapper)) *debouncer {
+	return &debouncer{
+		window:  window,
+		flush:   flush,
+		pending: map[string]*EventWrapper{},
+	}
+}
+
+// add merges the event with the pending one of the same object, or starts a new window for it
+func (d *debouncer) add(ew EventWrapper) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		d.flush(ew)
+		return
+	}
+	key := ew.Event.key
+	if pending, ok := d.pending[key]; ok {
+		if merge(&pending.Event, ew.Event) {
+			return
+		}
+		// the pending event can't absorb this one, notify it now and start a new window
+		delete(d.pending, key)
+		d.flush(*pending)
+	}
+
+	d.pending[key] = &ew
+	time.AfterFunc(d.window, func() { d.fire(key, &ew) })
+}
+
+// fire notifies the pending event of the object when its window ends
+func (d *debouncer) fire(key string, ew *EventWrapper) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// the window was already flushed by an event that couldn't be merged
+	if d.pending[key] != ew {
+		return
+	}
+	delete(d.pending, key)
+	d.flush(*ew)
+}
+
+// flushAll notifies the pending events without waiting for the end of their windows, before terminating
+func (d *debouncer) flushAll() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.closed = true
+	for key, ew := range d.pending {
+		delete(d.pending, key)
+		d.flush(*ew)
+	}
+}
+
+// merge folds the next event of an object into the pending one, it returns false if they can't be merged:
+//   - updates keep the old object of the first event and the new object of the last, so the diff covers all of them
+//   - updates after a create are part of the creation
+//   - a delete after a create becomes a transient event, a delete after updates a delete
+func merge(pending *Event, next Event) bool {
+	switch pending.eventType {
+	case "create":
+		switch next.eventType {
+		case "update":
+			pending.obj = next.obj
+			return true
+		case "delete":
+			pending.eventType = "transient"
+			if next.obj != nil {
+				pending.obj = next.obj
+			}
+			return true
+		}
+	case "update":
+		switch next.eventType {
+		case "update":
+			pending.obj = next.obj
+			return true
+		case "delete":
+			*pending = next
+			return true
+		}
+	}
+	return false
+}
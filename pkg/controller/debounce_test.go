@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
+)
+
+type flushed struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (f *flushed) add(ew EventWrapper) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, ew.Event)
+}
+
+func (f *flushed) get() []Event {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Event(nil), f.events...)
+}
+
+func configMap(data string) *api_v1.ConfigMap {
+	return &api_v1.ConfigMap{ObjectMeta: meta_v1.ObjectMeta{Name: "foo", Namespace: "default"}, Data: map[string]string{"key": data}}
+}
+
+func TestDebouncerFlushAll(t *testing.T) {
+	f := &flushed{}
+	d := newDebouncer(time.Hour, f.add)
+
+	d.add(EventWrapper{Event: Event{key: "default/foo", eventType: "update", oldObj: configMap("1"), obj: configMap("2")}})
+	d.add(EventWrapper{Event: Event{key: "default/bar", eventType: "create", obj: configMap("1")}})
+	assert.Empty(t, f.get())
+
+	// the pending events are flushed on termination, the next ones right away
+	d.flushAll()
+	assert.Len(t, f.get(), 2)
+	assert.Empty(t, d.pending)
+	d.add(EventWrapper{Event: Event{key: "default/foo", eventType: "update", oldObj: configMap("2"), obj: configMap("3")}})
+	assert.Len(t, f.get(), 3)
+}
+
+func TestDebouncer(t *testing.T) {
+	f := &flushed{}
+	d := newDebouncer(50*time.Millisecond, f.add)
+
+	v1, v2, v3 := configMap("1"), configMap("2"), configMap("3")
+	d.add(EventWrapper{Event: Event{key: "default/updated", eventType: "update", oldObj: v1, obj: v2}})
+	d.add(EventWrapper{Event: Event{key: "default/updated", eventType: "update", oldObj: v2, obj: v3}})
+	d.add(EventWrapper{Event: Event{key: "default/transient", eventType: "create", obj: v1}})
+	d.add(EventWrapper{Event: Event{key: "default/transient", eventType: "update", oldObj: v1, obj: v2}})
+	d.add(EventWrapper{Event: Event{key: "default/transient", eventType: "delete"}})
+	d.add(EventWrapper{Event: Event{key: "default/recreated", eventType: "delete", obj: v1}})
+	d.add(EventWrapper{Event: Event{key: "default/recreated", eventType: "create", obj: v2}})
+
+	// the delete can't absorb the create and is flushed right away
+	assert.Len(t, f.get(), 1)
+	assert.Eventually(t, func() bool { return len(f.get()) == 4 }, time.Second, 10*time.Millisecond)
+
+	byKey := map[string][]Event{}
+	for _, e := range f.get() {
+		byKey[e.key] = append(byKey[e.key], e)
+	}
+
+	updated := byKey["default/updated"]
+	assert.Len(t, updated, 1)
+	assert.Equal(t, "update", updated[0].eventType)
+	assert.Same(t, v1, updated[0].oldObj)
+	assert.Same(t, v3, updated[0].obj)
+
+	transient := byKey["default/transient"]
+	assert.Len(t, transient, 1)
+	assert.Equal(t, "transient", transient[0].eventType)
+	assert.Same(t, v2, transient[0].obj)
+
+	recreated := byKey["default/recreated"]
+	assert.Len(t, recreated, 2)
+	assert.Equal(t, "delete", recreated[0].eventType)
+	assert.Equal(t, "create", recreated[1].eventType)
+}
+
+func TestMerge(t *testing.T) {
+	v1, v2 := configMap("1"), configMap("2")
+
+	var Tests = []struct {
+		pending   string
+		next      string
+		merged    bool
+		eventType string
+	}{
+		{"create", "update", true, "create"},
+		{"create", "delete", true, "transient"},
+		{"update", "update", true, "update"},
+		{"update", "delete", true, "delete"},
+		{"delete", "create", false, "delete"},
+		{"transient", "create", false, "transient"},
+		{"create", "create", false, "create"},
+	}
+
+	for _, tt := range Tests {
+		pending := Event{eventType: tt.pending, oldObj: v1, obj: v1}
+		merged := merge(&pending, Event{eventType: tt.next, oldObj: v1, obj: v2})
+		assert.Equal(t, tt.merged, merged, tt.pending+"+"+tt.next)
+		assert.Equal(t, tt.eventType, pending.eventType, tt.pending+"+"+tt.next)
+	}
+}
+
+func TestDrainControllers(t *testing.T) {
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	c := &Controller{queue: queue, debouncer: newDebouncer(time.Hour, func(ew EventWrapper) { queue.Add(ew) })}
+	controllersMu.Lock()
+	started := controllers
+	controllers = []*Controller{c}
+	controllersMu.Unlock()
+	defer func() { controllers = started }()
+
+	f := &flushed{}
+	go func() {
+		for {
+			item, shutdown := queue.Get()
+			if shutdown {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+			f.add(item.(EventWrapper))
+			queue.Done(item)
+		}
+	}()
+
+	// the debounced events are processed before the handlers are stopped
+	c.debouncer.add(EventWrapper{Event: Event{key: "default/foo", eventType: "create", obj: configMap("1")}})
+	queue.Add(EventWrapper{Event: Event{key: "default/bar", eventType: "delete", obj: configMap("1")}})
+	drainControllers()
+	assert.Len(t, f.get(), 2)
+	assert.True(t, queue.ShuttingDown())
+}
@@ -0,0 +1,235 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/sirupsen/logrus"
+	api_v1 "k8s.io/api/core/v1"
+	events_v1 "k8s.io/api/events/v1"
+	"k8s.io/utils/strings/slices"
+)
+
+const defaultEventWindow = 5 * time.Minute
+
+// eventPipeline notifies the core events instead of their diffs, nil when disabled
+var eventPipeline *eventAggregator
+
+// k8sEvent is the part of a core/v1 or events.k8s.io/v1 Event the pipeline needs
+type k8sEvent struct {
+	eventType string
+	reason    string
+	message   string
+	involved  api_v1.ObjectReference
+	count     int32
+}
+
+// toK8sEvent reads a core/v1 or events.k8s.io/v1 Event, false for other objects
+func toK8sEvent(obj interface{}) (k8sEvent, bool) {
+	switch e := obj.(type) {
+	case *api_v1.Event:
+		count := e.Count
+		if e.Series != nil {
+			count = e.Series.Count
+		}
+		return k8sEvent{eventType: e.Type, reason: e.Reason, message: e.Message, involved: e.InvolvedObject, count: count}, true
+	case *events_v1.Event:
+		count := int32(1)
+		if e.Series != nil {
+			count = e.Series.Count
+		} else if e.DeprecatedCount > 0 {
+			count = e.DeprecatedCount
+		}
+		return k8sEvent{eventType: e.Type, reason: e.Reason, message: e.Note, involved: e.Regarding, count: count}, true
+	}
+	return k8sEvent{}, false
+}
+
+// eventGroup counts the repeats of an event within the window after it was notified
+type eventGroup struct {
+	repeats int32
+	// firstRepeat and lastRepeat are the times of the repeats within the window
+	firstRepeat time.Time
+	lastRepeat  time.Time
+	last        k8sEvent
+	c           *Controller
+	newEvent    Event
+	o           overrides
+}
+
+// eventAggregator forwards the first occurrence of an event right away, and its repeats within the window
+// as one summary, e.g. "FailedScheduling x14 in 4m". The event stays aggregated in the following windows
+// until one has no repeat
+type eventAggregator struct {
+	types         []string
+	reasons       []string
+	involvedKinds []string
+	window        time.Duration
+	now           func() time.Time
+	mu            sync.Mutex
+	groups        map[string]*eventGroup
+}
+
+func newEventAggregator(conf config.EventPipeline) *eventAggregator {
+	types := conf.Types
+	if len(types) == 0 {
+		types = []string{api_v1.EventTypeWarning}
+	}
+	window := time.Duration(conf.WindowSeconds) * time.Second
+	if window <= 0 {
+		window = defaultEventWindow
+	}
+	return &eventAggregator{
+		types:         types,
+		reasons:       conf.Reasons,
+		involvedKinds: conf.InvolvedKinds,
+		window:        window,
+		now:           time.Now,
+		groups:        map[string]*eventGroup{},
+	}
+}
+
+// match checks the type, reason and involved object kind of the event
+func (a *eventAggregator) match(e k8sEvent) bool {
+	if !slices.Contains(a.types, e.eventType) {
+		return false
+	}
+	if len(a.reasons) > 0 && !slices.Contains(a.reasons, e.reason) {
+		return false
+	}
+	if len(a.involvedKinds) > 0 && !slices.Contains(a.involvedKinds, e.involved.Kind) {
+		return false
+	}
+	return true
+}
+
+// occurrences returns how many times the event happened since its previous version
+func occurrences(e k8sEvent, old interface{}) int32 {
+	previous, ok := toK8sEvent(old)
+	if !ok || e.count <= previous.count {
+		return 1
+	}
+	if previous.count == 0 {
+		return e.count
+	}
+	return e.count - previous.count
+}
+
+// add notifies the first occurrence of the event or counts it as a repeat, false if the first occurrence must be notified
+func (a *eventAggregator) add(c *Controller, newEvent Event, o overrides, e k8sEvent, count int32) bool {
+	key := strings.Join([]string{e.involved.Kind, e.involved.Namespace, e.involved.Name, e.reason}, "/")
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if g, ok := a.groups[key]; ok {
+		now := a.now()
+		if g.repeats == 0 {
+			g.firstRepeat = now
+		}
+		g.repeats += count
+		g.lastRepeat = now
+		g.last, g.c, g.newEvent, g.o = e, c, newEvent, o
+		return true
+	}
+
+	a.groups[key] = &eventGroup{last: e, c: c, newEvent: newEvent, o: o}
+	time.AfterFunc(a.window, func() { a.flush(key) })
+	return false
+}
+
+// flush notifies the repeats of the event at the end of the window, the repeats of the next window being
+// aggregated too. The event is notified right away again once a window has no repeat
+func (a *eventAggregator) flush(key string) {
+	a.mu.Lock()
+	g := a.groups[key]
+	if g.repeats == 0 {
+		delete(a.groups, key)
+		a.mu.Unlock()
+		return
+	}
+	notified := *g
+	g.repeats = 0
+	time.AfterFunc(a.window, func() { a.flush(key) })
+	a.mu.Unlock()
+
+	details := fmt.Sprintf("%s x%d: %s", notified.last.reason, notified.repeats, notified.last.message)
+	if span := notified.lastRepeat.Sub(notified.firstRepeat); span >= time.Second {
+		details = fmt.Sprintf("%s x%d in %s: %s", notified.last.reason, notified.repeats, shortDuration(span), notified.last.message)
+	}
+	notified.c.notifyK8sEvent(notified.newEvent, notified.o, notified.last, details)
+}
+
+// shortDuration formats a duration without its zero units, e.g. 5m instead of 5m0s
+func shortDuration(d time.Duration) string {
+	if d >= time.Second {
+		d = d.Round(time.Second)
+	}
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// processK8sEvent notifies a created or updated core event through the pipeline
+func (c *Controller) processK8sEvent(newEvent Event, o overrides) {
+	e, ok := toK8sEvent(newEvent.obj)
+	if !ok || !eventPipeline.match(e) {
+		return
+	}
+	// events of the initial list happened before the start
+	if newEvent.eventType == "create" && newEvent.obj != nil {
+		if created := eventCreationTime(newEvent.obj); created.Before(serverStartTime) {
+			return
+		}
+	}
+
+	count := occurrences(e, newEvent.oldObj)
+	if eventPipeline.add(c, newEvent, o, e, count) {
+		logrus.Debugf("Aggregating %s of %s %s/%s", e.reason, e.involved.Kind, e.involved.Namespace, e.involved.Name)
+		return
+	}
+	c.notifyK8sEvent(newEvent, o, e, e.message)
+}
+
+func eventCreationTime(obj interface{}) time.Time {
+	switch e := obj.(type) {
+	case *api_v1.Event:
+		return e.CreationTimestamp.Time
+	case *events_v1.Event:
+		return e.CreationTimestamp.Time
+	}
+	return time.Time{}
+}
+
+// notifyK8sEvent sends a core event as an event of its involved object
+func (c *Controller) notifyK8sEvent(newEvent Event, o overrides, e k8sEvent, details string) {
+	severity := event.SeverityInfo
+	if e.eventType == api_v1.EventTypeWarning {
+		severity = event.SeverityWarning
+	}
+	// the severity rules match the core events by the kind Event
+	status := classifyReason(newEvent.resourceType, e.involved.Namespace, e.reason, severity)
+	if o.status != "" {
+		status = o.status
+	}
+
+	kbEvent := event.StatemonitorEvent{
+		Name:       e.involved.Name,
+		Namespace:  e.involved.Namespace,
+		Kind:       e.involved.Kind,
+		ApiVersion: e.involved.APIVersion,
+		Status:     status,
+		Reason:     e.reason,
+		Details:    details,
+	}
+	c.notifyFollowUp(kbEvent, newEvent, o.route)
+}
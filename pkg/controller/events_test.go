@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers"
+	"github.com/stretchr/testify/assert"
+	api_v1 "k8s.io/api/core/v1"
+	events_v1 "k8s.io/api/events/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func coreEvent(eventType string, reason string, count int32) *api_v1.Event {
+	return &api_v1.Event{
+		ObjectMeta:     meta_v1.ObjectMeta{Name: "api-x2.17a", Namespace: "default", CreationTimestamp: meta_v1.Now()},
+		InvolvedObject: api_v1.ObjectReference{Kind: "Pod", Namespace: "default", Name: "api-x2", APIVersion: "v1"},
+		Type:           eventType,
+		Reason:         reason,
+		Message:        "0/3 nodes are available",
+		Count:          count,
+	}
+}
+
+func TestToK8sEvent(t *testing.T) {
+	e, ok := toK8sEvent(coreEvent("Warning", "FailedScheduling", 3))
+	assert.True(t, ok)
+	assert.Equal(t, k8sEvent{eventType: "Warning", reason: "FailedScheduling", message: "0/3 nodes are available", involved: api_v1.ObjectReference{Kind: "Pod", Namespace: "default", Name: "api-x2", APIVersion: "v1"}, count: 3}, e)
+
+	e, ok = toK8sEvent(&events_v1.Event{Type: "Warning", Reason: "BackOff", Note: "Back-off restarting", Regarding: api_v1.ObjectReference{Kind: "Pod"}, Series: &events_v1.EventSeries{Count: 7}})
+	assert.True(t, ok)
+	assert.Equal(t, int32(7), e.count)
+	assert.Equal(t, "Back-off restarting", e.message)
+
+	_, ok = toK8sEvent(&api_v1.Pod{})
+	assert.False(t, ok)
+
+	assert.Equal(t, int32(1), occurrences(e, nil))
+	assert.Equal(t, int32(4), occurrences(k8sEvent{count: 7}, coreEvent("Warning", "BackOff", 3)))
+}
+
+func TestEventAggregatorMatch(t *testing.T) {
+	a := newEventAggregator(config.EventPipeline{})
+	assert.True(t, a.match(k8sEvent{eventType: "Warning"}))
+	assert.False(t, a.match(k8sEvent{eventType: "Normal"}))
+
+	a = newEventAggregator(config.EventPipeline{Types: []string{"Warning", "Normal"}, Reasons: []string{"BackOff"}, InvolvedKinds: []string{"Pod"}})
+	assert.True(t, a.match(k8sEvent{eventType: "Normal", reason: "BackOff", involved: api_v1.ObjectReference{Kind: "Pod"}}))
+	assert.False(t, a.match(k8sEvent{eventType: "Warning", reason: "FailedScheduling", involved: api_v1.ObjectReference{Kind: "Pod"}}))
+	assert.False(t, a.match(k8sEvent{eventType: "Warning", reason: "BackOff", involved: api_v1.ObjectReference{Kind: "Node"}}))
+}
+
+func TestEventPipeline(t *testing.T) {
+	slack := &recordingHandler{}
+	c := &Controller{eventHandlers: map[string]handlers.Handler{"slack": slack}}
+	eventPipeline = newEventAggregator(config.EventPipeline{})
+	eventPipeline.window = 50 * time.Millisecond
+	defer func() { eventPipeline = nil }()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	eventPipeline.now = func() time.Time { return now }
+
+	first := coreEvent("Warning", "FailedScheduling", 1)
+	c.processK8sEvent(Event{eventType: "create", resourceType: "Event", obj: first}, overrides{})
+	c.processK8sEvent(Event{eventType: "update", resourceType: "Event", oldObj: first, obj: coreEvent("Warning", "FailedScheduling", 9)}, overrides{})
+	now = now.Add(4 * time.Minute)
+	c.processK8sEvent(Event{eventType: "update", resourceType: "Event", oldObj: coreEvent("Warning", "FailedScheduling", 9), obj: coreEvent("Warning", "FailedScheduling", 15)}, overrides{})
+	// normal events aren't forwarded
+	c.processK8sEvent(Event{eventType: "create", resourceType: "Event", obj: coreEvent("Normal", "Scheduled", 1)}, overrides{})
+
+	events := slack.received()
+	assert.Len(t, events, 1)
+	assert.Equal(t, "Pod", events[0].Kind)
+	assert.Equal(t, "api-x2", events[0].Name)
+	assert.Equal(t, "FailedScheduling", events[0].Reason)
+	assert.Equal(t, "Warning", events[0].Status)
+	assert.Equal(t, "0/3 nodes are available", events[0].Details)
+
+	assert.Eventually(t, func() bool { return len(slack.received()) == 2 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "FailedScheduling x14 in 4m: 0/3 nodes are available", slack.received()[1].Details)
+
+	// the repeat following the summary is aggregated in the next window
+	c.processK8sEvent(Event{eventType: "update", resourceType: "Event", oldObj: coreEvent("Warning", "FailedScheduling", 15), obj: coreEvent("Warning", "FailedScheduling", 16)}, overrides{})
+	assert.Len(t, slack.received(), 2)
+	assert.Eventually(t, func() bool { return len(slack.received()) == 3 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "FailedScheduling x1: 0/3 nodes are available", slack.received()[2].Details)
+
+	// after a window without repeat, the event is notified right away
+	assert.Eventually(t, func() bool {
+		eventPipeline.mu.Lock()
+		defer eventPipeline.mu.Unlock()
+		return len(eventPipeline.groups) == 0
+	}, time.Second, 10*time.Millisecond)
+	c.processK8sEvent(Event{eventType: "update", resourceType: "Event", oldObj: coreEvent("Warning", "FailedScheduling", 16), obj: coreEvent("Warning", "FailedScheduling", 17)}, overrides{})
+	assert.Len(t, slack.received(), 4)
+	assert.Equal(t, "0/3 nodes are available", slack.received()[3].Details)
+}
+
+func TestShortDuration(t *testing.T) {
+	assert.Equal(t, "5m", shortDuration(5*time.Minute))
+	assert.Equal(t, "1h", shortDuration(time.Hour))
+	assert.Equal(t, "1h30m", shortDuration(90*time.Minute))
+	assert.Equal(t, "45s", shortDuration(45*time.Second))
+}
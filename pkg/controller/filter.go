@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/marvasgit/kubestatewatch/pkg/filter"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var filteredMetric = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "statemonitor_filtered_events_total",
+	Help: "The total number of events dropped by each filter",
+},
+	[]string{"Filter"})
+
+// matchFilter evaluates a filter and counts the events it drops.
+// Events are notified when the filter can't be evaluated, so errors don't hide changes
+func matchFilter(f *filter.Expression, in filter.Input, name string) bool {
+	match, err := f.Match(in)
+	if err != nil {
+		logrus.Warnf("Filter %s: %v", name, err)
+		return true
+	}
+	if !match {
+		filteredMetric.WithLabelValues(name).Inc()
+	}
+	return match
+}
+
+// needsFilterInput reports whether a filter of the resource or of the routes will be evaluated
+func (c *Controller) needsFilterInput(routeName string) bool {
+	return c.filter != nil || routeName != "" || routesHaveConditions()
+}
+
+// filterInput builds the variables the filters are evaluated against
+func filterInput(e event.StatemonitorEvent, newEvent Event) filter.Input {
+	in := filter.Input{
+		Event: map[string]interface{}{
+			"kind":       e.Kind,
+			"name":       e.Name,
+			"namespace":  e.Namespace,
+			"apiVersion": e.ApiVersion,
+			"reason":     e.Reason,
+			"status":     e.Status,
+			"severity":   event.SeverityFromStatus(e.Status),
+		},
+		Object:          toUnstructured(newEvent.obj),
+		NamespaceLabels: namespaceLabels(e.Namespace),
+	}
+	if newEvent.eventType == "update" {
+		in.OldObject = toUnstructured(newEvent.oldObj)
+	}
+	if e.Diff != "" {
+		if err := json.Unmarshal([]byte(e.Diff), &in.Diff); err != nil {
+			logrus.Warnf("Error in unmarshalling diff for filters %s", err)
+		}
+	}
+	return in
+}
+
+func toUnstructured(obj runtime.Object) map[string]interface{} {
+	if obj == nil {
+		return nil
+	}
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		logrus.Warnf("Error in converting object for filters %s", err)
+		return nil
+	}
+	return u
+}
+
+// notify sends the event to the handlers if it matches the filter of the resource and of its route,
+// the events of owned objects being rolled up on their owner. It returns false when the event was dropped by the resource filter
+func (c *Controller) notify(e event.StatemonitorEvent, newEvent Event, routeName string) bool {
+	e.Labels = eventLabels(e, newEvent)
+	e.Actor = eventActor(e, newEvent)
+	in, ok := c.matchResource(e, newEvent, routeName)
+	if !ok {
+		return false
+	}
+
+	if c.rollupEvent(e, newEvent, routeName) {
+		return true
+	}
+	c.dispatch(e, in, routeName)
+	return true
+}
+
+// notifyFollowUp sends an event about the object that isn't one of its changes, e.g. the result of a rollout,
+// to the handlers if it matches the filter of the resource and of its route
+func (c *Controller) notifyFollowUp(e event.StatemonitorEvent, newEvent Event, routeName string) {
+	e.Labels = eventLabels(e, newEvent)
+	if in, ok := c.matchResource(e, newEvent, routeName); ok {
+		c.dispatch(e, in, routeName)
+	}
+}
+
+// eventLabels returns the labels of the object of the event, nil for the events about another object like the core events
+func eventLabels(e event.StatemonitorEvent, newEvent Event) map[string]string {
+	if e.Labels != nil || e.Kind != newEvent.resourceType || newEvent.obj == nil {
+		return e.Labels
+	}
+	accessor, err := meta.Accessor(newEvent.obj)
+	if err != nil {
+		return nil
+	}
+	return accessor.GetLabels()
+}
+
+// eventActor returns the manager of the latest managed fields of the object of the event,
+// empty when the managed fields are dropped from the cache, see ResourceConfig.KeepManagedFields
+func eventActor(e event.StatemonitorEvent, newEvent Event) string {
+	if e.Actor != "" || e.Kind != newEvent.resourceType || newEvent.obj == nil {
+		return e.Actor
+	}
+	accessor, err := meta.Accessor(newEvent.obj)
+	if err != nil {
+		return ""
+	}
+	var actor string
+	var latest time.Time
+	for _, entry := range accessor.GetManagedFields() {
+		if entry.Time != nil && !entry.Time.Time.Before(latest) {
+			actor, latest = entry.Manager, entry.Time.Time
+		}
+	}
+	return actor
+}
+
+// matchResource builds the filter input of the event when needed and checks the filter of the resource
+func (c *Controller) matchResource(e event.StatemonitorEvent, newEvent Event, routeName string) (filter.Input, bool) {
+	var in filter.Input
+	if c.needsFilterInput(routeName) {
+		in = filterInput(e, newEvent)
+	}
+	if c.filter != nil && !matchFilter(c.filter, in, "resource/"+newEvent.resourceType) {
+		logrus.Debugf("Skipping %v %s (filtered)", newEvent.resourceType, newEvent.key)
+		return in, false
+	}
+	return in, true
+}
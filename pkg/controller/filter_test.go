@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/marvasgit/kubestatewatch/pkg/filter"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	apps_v1 "k8s.io/api/apps/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFilteredRoutes(t *testing.T) {
+	msteams, slack, smtp := &recordingHandler{}, &recordingHandler{}, &recordingHandler{}
+	c := &Controller{eventHandlers: map[string]handlers.Handler{"msteams": msteams, "slack": slack, "smtp": smtp}}
+	var err error
+	routes, err = getRoutes([]config.Route{
+		{Name: "images", Handlers: []string{"slack"}, Filter: `diff.exists(d, d.path.endsWith("/image"))`},
+		{Name: "deletes", Handlers: []string{"slack", "smtp"}, Filter: `event.reason == "Deleted"`},
+		{Name: "payments", Handlers: []string{"msteams"}, Filter: `event.namespace == "payments"`},
+	})
+	assert.NoError(t, err)
+	defer func() { routes = nil }()
+
+	imageChange := filter.Input{
+		Event: map[string]interface{}{"reason": "Updated", "namespace": "shop"},
+		Diff:  []interface{}{map[string]interface{}{"op": "replace", "path": "/spec/template/spec/containers/0/image"}},
+	}
+	deleted := filter.Input{Event: map[string]interface{}{"reason": "Deleted", "namespace": "shop"}}
+	scaled := filter.Input{Event: map[string]interface{}{"reason": "Updated", "namespace": "shop"}}
+
+	dropped := testutil.ToFloat64(filteredMetric.WithLabelValues("route/images"))
+	e := event.StatemonitorEvent{Name: "foo"}
+	c.dispatch(e, imageChange, "")
+	c.dispatch(e, deleted, "")
+	c.dispatch(e, scaled, "")
+	// annotated routes only receive the events matching their filter
+	c.dispatch(e, scaled, "payments")
+	c.dispatch(e, filter.Input{Event: map[string]interface{}{"namespace": "payments"}}, "payments")
+
+	assert.Len(t, slack.events, 3)
+	assert.Len(t, smtp.events, 2)
+	assert.Len(t, msteams.events, 2)
+	// the events dropped by the filters of the routes are counted
+	assert.Equal(t, dropped+2, testutil.ToFloat64(filteredMetric.WithLabelValues("route/images")))
+}
+
+func TestRouteFilterError(t *testing.T) {
+	slack, smtp := &recordingHandler{}, &recordingHandler{}
+	c := &Controller{eventHandlers: map[string]handlers.Handler{"slack": slack, "smtp": smtp}}
+	var err error
+	routes, err = getRoutes([]config.Route{{Name: "scaled", Handlers: []string{"slack"}, Filter: `object.spec.replicas > 1`}})
+	assert.NoError(t, err)
+	defer func() { routes = nil }()
+
+	// a filter failing to evaluate matches, like the filters of the resources
+	c.dispatch(event.StatemonitorEvent{Name: "foo"}, filter.Input{Event: map[string]interface{}{"reason": "Updated"}}, "")
+	assert.Len(t, slack.events, 1)
+	assert.Empty(t, smtp.events)
+}
+
+func TestGetRoutesInvalidFilter(t *testing.T) {
+	_, err := getRoutes([]config.Route{{Name: "broken", Filter: `event.reason ==`}})
+	assert.Error(t, err)
+}
+
+func TestNotifyResourceFilter(t *testing.T) {
+	slack := &recordingHandler{}
+	f, err := filter.CompileExpression(`object.spec.replicas > oldObject.spec.replicas`)
+	assert.NoError(t, err)
+	c := &Controller{eventHandlers: map[string]handlers.Handler{"slack": slack}, filter: f}
+
+	replicas := func(n int32) *apps_v1.Deployment {
+		return &apps_v1.Deployment{ObjectMeta: meta_v1.ObjectMeta{Name: "api"}, Spec: apps_v1.DeploymentSpec{Replicas: &n}}
+	}
+	e := event.StatemonitorEvent{Name: "api", Kind: "Deployment", Reason: "Updated"}
+
+	assert.True(t, c.notify(e, Event{eventType: "update", resourceType: "Deployment", obj: replicas(3), oldObj: replicas(2)}, ""))
+	assert.False(t, c.notify(e, Event{eventType: "update", resourceType: "Deployment", obj: replicas(1), oldObj: replicas(2)}, ""))
+	assert.Len(t, slack.events, 1)
+}
+
+func TestEventLabels(t *testing.T) {
+	deployment := &apps_v1.Deployment{ObjectMeta: meta_v1.ObjectMeta{Name: "api", Labels: map[string]string{"team": "payments"}}}
+	newEvent := Event{resourceType: "Deployment", obj: deployment}
+
+	assert.Equal(t, map[string]string{"team": "payments"}, eventLabels(event.StatemonitorEvent{Kind: "Deployment"}, newEvent))
+	// the core events are about another object than the Event
+	assert.Nil(t, eventLabels(event.StatemonitorEvent{Kind: "Pod"}, Event{resourceType: "Event", obj: deployment}))
+}
+
+func TestEventActor(t *testing.T) {
+	at := func(hour int) *meta_v1.Time {
+		t := meta_v1.NewTime(time.Date(2024, 1, 1, hour, 0, 0, 0, time.UTC))
+		return &t
+	}
+	deployment := &apps_v1.Deployment{ObjectMeta: meta_v1.ObjectMeta{Name: "api", ManagedFields: []meta_v1.ManagedFieldsEntry{
+		{Manager: "kubectl-client-side-apply", Time: at(9)},
+		{Manager: "helm", Time: at(11)},
+		{Manager: "kube-controller-manager", Subresource: "status", Time: at(10)},
+	}}}
+
+	assert.Equal(t, "helm", eventActor(event.StatemonitorEvent{Kind: "Deployment"}, Event{resourceType: "Deployment", obj: deployment}))
+	// the managed fields are dropped from the cache by default
+	assert.Empty(t, eventActor(event.StatemonitorEvent{Kind: "Deployment"}, Event{resourceType: "Deployment", obj: &apps_v1.Deployment{}}))
+}
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/sirupsen/logrus"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/metadata/metadatainformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/strings/slices"
+)
+
+// metadataOnly reports whether the resource is watched only for add and/or delete events,
+// in which case the full objects are not needed to build the notifications
+func metadataOnly(resourceConfig config.ResourceConfig) bool {
+	if len(resourceConfig.IncludeEvenTypes) == 0 {
+		return false
+	}
+	return !slices.Contains(resourceConfig.IncludeEvenTypes, "update")
+}
+
+// newInformer returns a metadata-only informer (PartialObjectMetadata) for the resources watched only for add/delete,
+// and a typed informer using lw for the resources that need the full objects to compute the update diffs.
+// The label and field selectors of the resource are applied server-side in both cases
+func newInformer(metadataClient metadata.Interface, gvr schema.GroupVersionResource, ns string, resourceConfig config.ResourceConfig, lw *cache.ListWatch, objType runtime.Object) cache.SharedIndexInformer {
+	tweak, err := newSelectorTweak(resourceConfig)
+	if err != nil {
+		logrus.Fatalf("error loading selectors for %s: %v", gvr.String(), err)
+	}
+
+	if metadataClient != nil && metadataOnly(resourceConfig) {
+		logrus.Infof("Using metadata-only informer for %s", gvr.String())
+		return metadatainformer.NewFilteredMetadataInformer(metadataClient, gvr, ns, 0, cache.Indexers{}, tweak).Informer()
+	}
+
+	return cache.NewSharedIndexInformer(
+		withListOptions(lw, tweak),
+		objType,
+		0, //Skip resync
+		cache.Indexers{},
+	)
+}
+
+// newSelectorTweak validates the label and field selectors of the resource
+// and returns the function setting them on the list and watch options
+func newSelectorTweak(resourceConfig config.ResourceConfig) (func(options *meta_v1.ListOptions), error) {
+	labelSelector, err := labels.Parse(resourceConfig.LabelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label selector %q: %v", resourceConfig.LabelSelector, err)
+	}
+	fieldSelector, err := fields.ParseSelector(resourceConfig.FieldSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid field selector %q: %v", resourceConfig.FieldSelector, err)
+	}
+
+	return func(options *meta_v1.ListOptions) {
+		if !labelSelector.Empty() {
+			options.LabelSelector = labelSelector.String()
+		}
+		if !fieldSelector.Empty() {
+			options.FieldSelector = fieldSelector.String()
+		}
+	}, nil
+}
+
+// withListOptions returns a ListWatch applying tweak to the options before listing and watching with lw
+func withListOptions(lw *cache.ListWatch, tweak func(options *meta_v1.ListOptions)) *cache.ListWatch {
+	return &cache.ListWatch{
+		ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+			tweak(&options)
+			return lw.ListFunc(options)
+		},
+		WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+			tweak(&options)
+			return lw.WatchFunc(options)
+		},
+		DisableChunking: lw.DisableChunking,
+	}
+}
@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/stretchr/testify/assert"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestMetadataOnly(t *testing.T) {
+	var Tests = []struct {
+		includeEvenTypes []string
+		metadataOnly     bool
+	}{
+		{nil, false},
+		{[]string{"add"}, true},
+		{[]string{"add", "delete"}, true},
+		{[]string{"delete"}, true},
+		{[]string{"update"}, false},
+		{[]string{"add", "update", "delete"}, false},
+	}
+
+	for _, tt := range Tests {
+		assert.Equal(t, tt.metadataOnly, metadataOnly(config.ResourceConfig{IncludeEvenTypes: tt.includeEvenTypes}), "%v", tt.includeEvenTypes)
+	}
+}
+
+func TestNewInformerMetadataOnly(t *testing.T) {
+	scheme := metadatafake.NewTestScheme()
+	assert.NoError(t, meta_v1.AddMetaToScheme(scheme))
+	pod := &meta_v1.PartialObjectMetadata{
+		TypeMeta:   meta_v1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: meta_v1.ObjectMeta{Name: "foo", Namespace: "default"},
+	}
+	client := metadatafake.NewSimpleMetadataClient(scheme, pod)
+
+	lw := &cache.ListWatch{
+		ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+			t.Fatal("typed informer used for a metadata-only resource")
+			return nil, nil
+		},
+		WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+			return watch.NewEmptyWatch(), nil
+		},
+	}
+
+	informer := newInformer(client, api_v1.SchemeGroupVersion.WithResource("pods"), "", config.ResourceConfig{IncludeEvenTypes: []string{"add", "delete"}}, lw, &api_v1.Pod{})
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go informer.Run(stopCh)
+	assert.True(t, cache.WaitForCacheSync(stopCh, informer.HasSynced))
+
+	obj, exists, err := informer.GetStore().GetByKey("default/foo")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	assert.IsType(t, &meta_v1.PartialObjectMetadata{}, obj)
+}
+
+func TestNewSelectorTweak(t *testing.T) {
+	var Tests = []struct {
+		labelSelector string
+		fieldSelector string
+		expected      meta_v1.ListOptions
+		err           bool
+	}{
+		{"", "", meta_v1.ListOptions{LabelSelector: "keep", FieldSelector: "keep"}, false},
+		{"tier=critical", "", meta_v1.ListOptions{LabelSelector: "tier=critical", FieldSelector: "keep"}, false},
+		{"app.kubernetes.io/managed-by!=Helm", "type=Warning", meta_v1.ListOptions{LabelSelector: "app.kubernetes.io/managed-by!=Helm", FieldSelector: "type=Warning"}, false},
+		{"tier in (a,", "", meta_v1.ListOptions{}, true},
+		{"", "type", meta_v1.ListOptions{}, true},
+	}
+
+	for _, tt := range Tests {
+		tweak, err := newSelectorTweak(config.ResourceConfig{LabelSelector: tt.labelSelector, FieldSelector: tt.fieldSelector})
+		if tt.err {
+			assert.Error(t, err)
+			continue
+		}
+		assert.NoError(t, err)
+
+		options := meta_v1.ListOptions{LabelSelector: "keep", FieldSelector: "keep"}
+		tweak(&options)
+		assert.Equal(t, tt.expected, options)
+	}
+}
+
+func TestNewInformerSelectors(t *testing.T) {
+	var listOptions meta_v1.ListOptions
+	lw := &cache.ListWatch{
+		ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+			listOptions = options
+			return &api_v1.EventList{}, nil
+		},
+		WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+			return watch.NewEmptyWatch(), nil
+		},
+	}
+
+	rc := config.ResourceConfig{LabelSelector: "tier=critical", FieldSelector: "type=Warning"}
+	informer := newInformer(nil, api_v1.SchemeGroupVersion.WithResource("events"), "", rc, lw, &api_v1.Event{})
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go informer.Run(stopCh)
+	assert.True(t, cache.WaitForCacheSync(stopCh, informer.HasSynced))
+
+	assert.Equal(t, "tier=critical", listOptions.LabelSelector)
+	assert.Equal(t, "type=Warning", listOptions.FieldSelector)
+}
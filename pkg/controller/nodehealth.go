@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/sirupsen/logrus"
+	api_v1 "k8s.io/api/core/v1"
+)
+
+// Reasons of the node events
+const (
+	ReasonNodeReady             = "NodeReady"
+	ReasonNodeNotReady          = "NodeNotReady"
+	ReasonNodeRebooted          = "NodeRebooted"
+	ReasonNodeKubeletChanged    = "NodeKubeletVersionChanged"
+	ReasonNodeCordoned          = "NodeCordoned"
+	ReasonNodeUncordoned        = "NodeUncordoned"
+	ReasonNodeTaintAdded        = "NodeTaintAdded"
+	ReasonNodeTaintRemoved      = "NodeTaintRemoved"
+	nodeConditionResolvedSuffix = "Resolved"
+)
+
+// nodeHealthEnabled detects the changes of the nodes conditions, reboots, versions, cordons and taints
+var nodeHealthEnabled bool
+
+// nodePressureConditions are the conditions of a node which are a problem when true
+var nodePressureConditions = map[api_v1.NodeConditionType]string{
+	api_v1.NodeMemoryPressure:     event.SeverityWarning,
+	api_v1.NodeDiskPressure:       event.SeverityWarning,
+	api_v1.NodePIDPressure:        event.SeverityWarning,
+	api_v1.NodeNetworkUnavailable: event.SeverityCritical,
+}
+
+// nodeChange is a change of a node's health or configuration
+type nodeChange struct {
+	reason   string
+	details  string
+	severity string
+}
+
+func nodeConditions(node *api_v1.Node) map[api_v1.NodeConditionType]api_v1.NodeCondition {
+	conditions := map[api_v1.NodeConditionType]api_v1.NodeCondition{}
+	for _, c := range node.Status.Conditions {
+		conditions[c.Type] = c
+	}
+	return conditions
+}
+
+func conditionDetails(c api_v1.NodeCondition) string {
+	if c.Reason == "" && c.Message == "" {
+		return fmt.Sprintf("%s is %s", c.Type, c.Status)
+	}
+	return fmt.Sprintf("%s is %s, %s: %s", c.Type, c.Status, c.Reason, c.Message)
+}
+
+func taintString(t api_v1.Taint) string {
+	if t.Value == "" {
+		return fmt.Sprintf("%s:%s", t.Key, t.Effect)
+	}
+	return fmt.Sprintf("%s=%s:%s", t.Key, t.Value, t.Effect)
+}
+
+// nodeChanges compares the two versions of a node and returns the changes of its conditions,
+// reboots, kubelet version, cordon and taints
+func nodeChanges(old *api_v1.Node, node *api_v1.Node) []nodeChange {
+	var changes []nodeChange
+
+	oldConditions, conditions := nodeConditions(old), nodeConditions(node)
+	if ready, ok := conditions[api_v1.NodeReady]; ok {
+		wasReady := oldConditions[api_v1.NodeReady].Status == api_v1.ConditionTrue
+		isReady := ready.Status == api_v1.ConditionTrue
+		if wasReady && !isReady {
+			changes = append(changes, nodeChange{ReasonNodeNotReady, conditionDetails(ready), event.SeverityCritical})
+		} else if !wasReady && isReady && oldConditions[api_v1.NodeReady].Status != "" {
+			changes = append(changes, nodeChange{ReasonNodeReady, conditionDetails(ready), event.SeverityInfo})
+		}
+	}
+	for _, t := range []api_v1.NodeConditionType{api_v1.NodeMemoryPressure, api_v1.NodeDiskPressure, api_v1.NodePIDPressure, api_v1.NodeNetworkUnavailable} {
+		c, ok := conditions[t]
+		if !ok || c.Status == oldConditions[t].Status {
+			continue
+		}
+		if c.Status == api_v1.ConditionTrue {
+			changes = append(changes, nodeChange{"Node" + string(t), conditionDetails(c), nodePressureConditions[t]})
+		} else if oldConditions[t].Status == api_v1.ConditionTrue {
+			changes = append(changes, nodeChange{"Node" + string(t) + nodeConditionResolvedSuffix, conditionDetails(c), event.SeverityInfo})
+		}
+	}
+
+	oldInfo, info := old.Status.NodeInfo, node.Status.NodeInfo
+	if oldInfo.BootID != "" && info.BootID != "" && oldInfo.BootID != info.BootID {
+		changes = append(changes, nodeChange{ReasonNodeRebooted, fmt.Sprintf("boot id %s", info.BootID), event.SeverityCritical})
+	}
+	if oldInfo.KubeletVersion != "" && info.KubeletVersion != "" && oldInfo.KubeletVersion != info.KubeletVersion {
+		changes = append(changes, nodeChange{ReasonNodeKubeletChanged, fmt.Sprintf("kubelet %s -> %s", oldInfo.KubeletVersion, info.KubeletVersion), event.SeverityInfo})
+	}
+
+	if !old.Spec.Unschedulable && node.Spec.Unschedulable {
+		changes = append(changes, nodeChange{ReasonNodeCordoned, "node is unschedulable", event.SeverityWarning})
+	} else if old.Spec.Unschedulable && !node.Spec.Unschedulable {
+		changes = append(changes, nodeChange{ReasonNodeUncordoned, "node is schedulable", event.SeverityInfo})
+	}
+
+	oldTaints := map[string]bool{}
+	for _, t := range old.Spec.Taints {
+		oldTaints[taintString(t)] = true
+	}
+	taints := map[string]bool{}
+	for _, t := range node.Spec.Taints {
+		taints[taintString(t)] = true
+		if !oldTaints[taintString(t)] {
+			changes = append(changes, nodeChange{ReasonNodeTaintAdded, taintString(t), event.SeverityWarning})
+		}
+	}
+	for _, t := range old.Spec.Taints {
+		if !taints[taintString(t)] {
+			changes = append(changes, nodeChange{ReasonNodeTaintRemoved, taintString(t), event.SeverityInfo})
+		}
+	}
+	return changes
+}
+
+// analyzeNode notifies the changes of an updated node
+func (c *Controller) analyzeNode(newEvent Event, o overrides) {
+	node, ok := newEvent.obj.(*api_v1.Node)
+	if !ok {
+		return
+	}
+	old, ok := newEvent.oldObj.(*api_v1.Node)
+	if !ok {
+		return
+	}
+
+	for _, change := range nodeChanges(old, node) {
+		logrus.Infof("Node %s %s: %s", newEvent.key, change.reason, change.details)
+		c.notifyReason(newEvent, o, change.reason, change.details, change.severity)
+	}
+}
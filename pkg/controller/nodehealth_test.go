@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers"
+	"github.com/stretchr/testify/assert"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func node(ready api_v1.ConditionStatus, memoryPressure api_v1.ConditionStatus) *api_v1.Node {
+	return &api_v1.Node{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "node-1"},
+		Status: api_v1.NodeStatus{
+			Conditions: []api_v1.NodeCondition{
+				{Type: api_v1.NodeReady, Status: ready, Reason: "KubeletReady", Message: "kubelet is posting ready status"},
+				{Type: api_v1.NodeMemoryPressure, Status: memoryPressure},
+			},
+			NodeInfo: api_v1.NodeSystemInfo{BootID: "boot-1", KubeletVersion: "v1.27.3"},
+		},
+	}
+}
+
+func TestNodeChanges(t *testing.T) {
+	healthy := node(api_v1.ConditionTrue, api_v1.ConditionFalse)
+
+	notReady := node(api_v1.ConditionUnknown, api_v1.ConditionFalse)
+	assert.Equal(t, []nodeChange{{ReasonNodeNotReady, "Ready is Unknown, KubeletReady: kubelet is posting ready status", event.SeverityCritical}}, nodeChanges(healthy, notReady))
+	assert.Equal(t, ReasonNodeReady, nodeChanges(notReady, healthy)[0].reason)
+
+	pressure := node(api_v1.ConditionTrue, api_v1.ConditionTrue)
+	assert.Equal(t, []nodeChange{{"NodeMemoryPressure", "MemoryPressure is True", event.SeverityWarning}}, nodeChanges(healthy, pressure))
+	assert.Equal(t, []nodeChange{{"NodeMemoryPressureResolved", "MemoryPressure is False", event.SeverityInfo}}, nodeChanges(pressure, healthy))
+
+	rebooted := healthy.DeepCopy()
+	rebooted.Status.NodeInfo.BootID = "boot-2"
+	rebooted.Status.NodeInfo.KubeletVersion = "v1.28.1"
+	assert.Equal(t, []nodeChange{
+		{ReasonNodeRebooted, "boot id boot-2", event.SeverityCritical},
+		{ReasonNodeKubeletChanged, "kubelet v1.27.3 -> v1.28.1", event.SeverityInfo},
+	}, nodeChanges(healthy, rebooted))
+
+	cordoned := healthy.DeepCopy()
+	cordoned.Spec.Unschedulable = true
+	cordoned.Spec.Taints = []api_v1.Taint{{Key: "node.kubernetes.io/unschedulable", Effect: api_v1.TaintEffectNoSchedule}}
+	assert.Equal(t, []nodeChange{
+		{ReasonNodeCordoned, "node is unschedulable", event.SeverityWarning},
+		{ReasonNodeTaintAdded, "node.kubernetes.io/unschedulable:NoSchedule", event.SeverityWarning},
+	}, nodeChanges(healthy, cordoned))
+	assert.Equal(t, []nodeChange{
+		{ReasonNodeUncordoned, "node is schedulable", event.SeverityInfo},
+		{ReasonNodeTaintRemoved, "node.kubernetes.io/unschedulable:NoSchedule", event.SeverityInfo},
+	}, nodeChanges(cordoned, healthy))
+
+	// heartbeats don't change anything
+	heartbeat := healthy.DeepCopy()
+	heartbeat.Status.Conditions[0].LastHeartbeatTime = meta_v1.Now()
+	assert.Empty(t, nodeChanges(healthy, heartbeat))
+}
+
+func TestAnalyzeNode(t *testing.T) {
+	slack := &recordingHandler{}
+	c := &Controller{eventHandlers: map[string]handlers.Handler{"slack": slack}}
+
+	newEvent := Event{key: "node-1", eventType: "update", resourceType: "Node", apiVersion: V1,
+		oldObj: node(api_v1.ConditionTrue, api_v1.ConditionFalse), obj: node(api_v1.ConditionFalse, api_v1.ConditionFalse)}
+	c.analyzeNode(newEvent, overrides{})
+
+	events := slack.received()
+	assert.Len(t, events, 1)
+	assert.Equal(t, ReasonNodeNotReady, events[0].Reason)
+	assert.Equal(t, "Danger", events[0].Status)
+	assert.Equal(t, "Node `node-1` is Not Ready : \nNodeNotReady\nReady is False, KubeletReady: kubelet is posting ready status", events[0].Message())
+}
+
+func TestWatchedKey(t *testing.T) {
+	namespaces = []string{"default"}
+	defer func() { namespaces = nil }()
+
+	assert.True(t, watchedKey("Pod", "default/api"))
+	assert.False(t, watchedKey("Pod", "kube-system/api"))
+	assert.True(t, watchedKey("Node", "node-1"))
+	assert.True(t, watchedKey("Namespace", "default"))
+	assert.False(t, watchedKey("Namespace", "kube-system"))
+}
@@ -0,0 +1,167 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// maxOwnerDepth bounds the ownerReferences chains, Pod -> ReplicaSet -> Deployment is 2
+	maxOwnerDepth = 5
+	// maxCachedOwners bounds the owners looked up with the API kept in the cache, the expired ones being evicted first
+	maxCachedOwners = 10000
+)
+
+// ownerCacheTTL is how long the owners looked up with the API are cached, the owners of a workload rarely changing
+var ownerCacheTTL = time.Minute
+
+// ownerResources are the kinds owning other objects, looked up with the metadata client when they are not watched
+var ownerResources = map[string]schema.GroupVersionResource{
+	"Deployment":            {Group: "apps", Version: "v1", Resource: "deployments"},
+	"ReplicaSet":            {Group: "apps", Version: "v1", Resource: "replicasets"},
+	"StatefulSet":           {Group: "apps", Version: "v1", Resource: "statefulsets"},
+	"DaemonSet":             {Group: "apps", Version: "v1", Resource: "daemonsets"},
+	"ReplicationController": {Group: "", Version: "v1", Resource: "replicationcontrollers"},
+	"Job":                   {Group: "batch", Version: "v1", Resource: "jobs"},
+	"CronJob":               {Group: "batch", Version: "v1", Resource: "cronjobs"},
+}
+
+// owner identifies the object owning a watched object
+type owner struct {
+	kind       string
+	apiVersion string
+	namespace  string
+	name       string
+}
+
+func (o owner) String() string {
+	return fmt.Sprintf("%s %s/%s", o.kind, o.namespace, o.name)
+}
+
+// ownerStores holds the caches of the watched resources by kind, to resolve owners without calling the API
+var (
+	ownerStoresMu sync.RWMutex
+	ownerStores   = map[string][]cache.Store{}
+)
+
+// ownerClient looks up the owners that are not watched, nil to only use the watched resources
+var ownerClient metadata.Interface
+
+// cachedOwner is an owner looked up with the API, nil when it doesn't exist
+type cachedOwner struct {
+	obj     meta_v1.Object
+	expires time.Time
+}
+
+// ownerCache holds the owners looked up with the API, the events of the children of an owner only calling the API once per ownerCacheTTL
+var (
+	ownerCacheMu sync.Mutex
+	ownerCache   = map[owner]cachedOwner{}
+)
+
+func registerOwnerStore(kind string, store cache.Store) {
+	ownerStoresMu.Lock()
+	defer ownerStoresMu.Unlock()
+	ownerStores[kind] = append(ownerStores[kind], store)
+}
+
+// watchedKind reports whether the resource of the kind is watched
+func watchedKind(kind string) bool {
+	ownerStoresMu.RLock()
+	defer ownerStoresMu.RUnlock()
+	return len(ownerStores[kind]) > 0
+}
+
+// lookupOwner returns the metadata of an owner from the watched resources, or from the API
+func lookupOwner(o owner) meta_v1.Object {
+	ownerStoresMu.RLock()
+	stores := ownerStores[o.kind]
+	ownerStoresMu.RUnlock()
+
+	key := o.namespace + "/" + o.name
+	for _, store := range stores {
+		if obj, exists, err := store.GetByKey(key); err == nil && exists {
+			if accessor, err := meta.Accessor(obj); err == nil {
+				return accessor
+			}
+		}
+	}
+
+	gvr, ok := ownerResources[o.kind]
+	if !ok || ownerClient == nil {
+		return nil
+	}
+	if obj, ok := cachedOwnerOf(o); ok {
+		return obj
+	}
+	obj, err := ownerClient.Resource(gvr).Namespace(o.namespace).Get(context.Background(), o.name, meta_v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		// the deleted owners are cached too, their children being deleted with them
+		cacheOwner(o, nil)
+		return nil
+	} else if err != nil {
+		logrus.Debugf("Error looking up owner %s: %v", o, err)
+		return nil
+	}
+	cacheOwner(o, obj)
+	return obj
+}
+
+// cachedOwnerOf returns the owner from the cache, false if it isn't cached or expired
+func cachedOwnerOf(o owner) (meta_v1.Object, bool) {
+	ownerCacheMu.Lock()
+	defer ownerCacheMu.Unlock()
+	cached, ok := ownerCache[o]
+	if !ok || time.Now().After(cached.expires) {
+		return nil, false
+	}
+	return cached.obj, true
+}
+
+func cacheOwner(o owner, obj meta_v1.Object) {
+	ownerCacheMu.Lock()
+	defer ownerCacheMu.Unlock()
+	now := time.Now()
+	if len(ownerCache) >= maxCachedOwners {
+		for key, cached := range ownerCache {
+			if now.After(cached.expires) {
+				delete(ownerCache, key)
+			}
+		}
+		if len(ownerCache) >= maxCachedOwners {
+			ownerCache = map[owner]cachedOwner{}
+		}
+	}
+	ownerCache[o] = cachedOwner{obj: obj, expires: now.Add(ownerCacheTTL)}
+}
+
+// topOwner follows the controller ownerReferences of an object up to the top-level owner, false if the object has no owner
+func topOwner(obj interface{}) (owner, bool) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return owner{}, false
+	}
+
+	var top owner
+	found := false
+	for depth := 0; depth < maxOwnerDepth && accessor != nil; depth++ {
+		ref := meta_v1.GetControllerOfNoCopy(accessor)
+		if ref == nil {
+			break
+		}
+		top = owner{kind: ref.Kind, apiVersion: ref.APIVersion, namespace: accessor.GetNamespace(), name: ref.Name}
+		found = true
+		accessor = lookupOwner(top)
+	}
+	return top, found
+}
@@ -0,0 +1,301 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/sirupsen/logrus"
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const defaultPendingTimeout = 5 * time.Minute
+
+// stableRunTime is how long a container must have run before terminating for its crashes to be a new incident,
+// containers without readiness probe being ready between the restarts of a crash loop
+const stableRunTime = 10 * time.Minute
+
+// Reasons of the pod failure events
+const (
+	ReasonCrashLoopBackOff = "CrashLoopBackOff"
+	ReasonOOMKilled        = "OOMKilled"
+	ReasonContainerFailed  = "ContainerFailed"
+	ReasonImagePullBackOff = "ImagePullBackOff"
+	ReasonEvicted          = "Evicted"
+	ReasonPendingTimeout   = "PendingTimeout"
+)
+
+// podIncidentSeverities are the severities of the pod failures matching no severity rule
+var podIncidentSeverities = map[string]string{
+	ReasonCrashLoopBackOff: event.SeverityCritical,
+	ReasonOOMKilled:        event.SeverityCritical,
+	ReasonContainerFailed:  event.SeverityWarning,
+	ReasonImagePullBackOff: event.SeverityWarning,
+	ReasonEvicted:          event.SeverityWarning,
+	ReasonPendingTimeout:   event.SeverityWarning,
+}
+
+// podHealth detects the failures of the pods on their updates, nil when disabled
+var podHealth *podAnalyzer
+
+// podIncident is a failure of a pod or of one of its containers
+type podIncident struct {
+	reason    string
+	container string
+	details   string
+}
+
+// podAnalyzer notifies each failure of a container once, until the container is ready again
+type podAnalyzer struct {
+	pendingTimeout time.Duration
+	mu             sync.Mutex
+	// incidents notified by pod uid, then container/reason
+	incidents map[string]map[string]bool
+	// pending pods waiting for their timeout by pod uid, nil for the pods already pending at the start
+	pending map[string]*time.Timer
+}
+
+func newPodAnalyzer(conf config.PodHealth) *podAnalyzer {
+	pendingTimeout := time.Duration(conf.PendingSeconds) * time.Second
+	if pendingTimeout <= 0 {
+		pendingTimeout = defaultPendingTimeout
+	}
+	return &podAnalyzer{
+		pendingTimeout: pendingTimeout,
+		incidents:      map[string]map[string]bool{},
+		pending:        map[string]*time.Timer{},
+	}
+}
+
+// containerIncidents compares the container statuses of a pod update and returns the new failures
+func containerIncidents(old []api_v1.ContainerStatus, new []api_v1.ContainerStatus) []podIncident {
+	previous := map[string]api_v1.ContainerStatus{}
+	for _, cs := range old {
+		previous[cs.Name] = cs
+	}
+
+	var incidents []podIncident
+	for _, cs := range new {
+		prev := previous[cs.Name]
+
+		crashLooping := false
+		if w := cs.State.Waiting; w != nil {
+			switch w.Reason {
+			case "CrashLoopBackOff":
+				crashLooping = true
+				incidents = append(incidents, podIncident{reason: ReasonCrashLoopBackOff, container: cs.Name, details: containerDetails(cs, w.Message)})
+			case "ImagePullBackOff", "ErrImagePull":
+				incidents = append(incidents, podIncident{reason: ReasonImagePullBackOff, container: cs.Name, details: containerDetails(cs, w.Reason+": "+w.Message)})
+			}
+		}
+
+		// a termination is new when the container restarted or just terminated
+		terminated := cs.State.Terminated
+		if terminated == nil && cs.RestartCount > prev.RestartCount {
+			terminated = cs.LastTerminationState.Terminated
+		} else if terminated != nil && prev.State.Terminated != nil {
+			terminated = nil
+		}
+		if terminated != nil && (terminated.Reason == "OOMKilled" || terminated.ExitCode != 0) {
+			reason := ReasonContainerFailed
+			if terminated.Reason == "OOMKilled" {
+				reason = ReasonOOMKilled
+			} else if crashLooping {
+				// the CrashLoopBackOff of the same update already describes the failure
+				continue
+			}
+			incidents = append(incidents, podIncident{reason: reason, container: cs.Name, details: containerDetails(cs, "")})
+		}
+	}
+	return incidents
+}
+
+// containerDetails describes the restarts and last termination of a container
+func containerDetails(cs api_v1.ContainerStatus, message string) string {
+	details := []string{fmt.Sprintf("container %s", cs.Name), fmt.Sprintf("restarts %d", cs.RestartCount)}
+	if message != "" {
+		details = append(details, message)
+	}
+	terminated := cs.State.Terminated
+	if terminated == nil {
+		terminated = cs.LastTerminationState.Terminated
+	}
+	if terminated != nil {
+		termination := fmt.Sprintf("last termination: %s (exit code %d)", terminated.Reason, terminated.ExitCode)
+		if terminated.Message != "" {
+			termination += " " + strings.TrimSpace(terminated.Message)
+		}
+		details = append(details, termination)
+	}
+	return strings.Join(details, ", ")
+}
+
+// analyze returns the failures of a pod update that weren't notified yet
+func (a *podAnalyzer) analyze(old *api_v1.Pod, pod *api_v1.Pod) []podIncident {
+	if old == nil {
+		old = &api_v1.Pod{}
+	}
+
+	incidents := containerIncidents(old.Status.InitContainerStatuses, pod.Status.InitContainerStatuses)
+	incidents = append(incidents, containerIncidents(old.Status.ContainerStatuses, pod.Status.ContainerStatuses)...)
+	if pod.Status.Phase == api_v1.PodFailed && pod.Status.Reason == "Evicted" && old.Status.Phase != api_v1.PodFailed {
+		incidents = append(incidents, podIncident{reason: ReasonEvicted, details: pod.Status.Message})
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	notified := a.incidents[string(pod.UID)]
+	if notified == nil {
+		notified = map[string]bool{}
+		a.incidents[string(pod.UID)] = notified
+	}
+	restarts := map[string]int32{}
+	for _, cs := range append(old.Status.InitContainerStatuses, old.Status.ContainerStatuses...) {
+		restarts[cs.Name] = cs.RestartCount
+	}
+	for _, cs := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		// image pulls are over once the container is ready, crashes when it restarts after running long enough
+		if cs.Ready {
+			delete(notified, cs.Name+"/"+ReasonImagePullBackOff)
+		}
+		t := cs.LastTerminationState.Terminated
+		if t != nil && cs.RestartCount > restarts[cs.Name] && t.FinishedAt.Sub(t.StartedAt.Time) >= stableRunTime {
+			for _, reason := range []string{ReasonCrashLoopBackOff, ReasonOOMKilled, ReasonContainerFailed} {
+				delete(notified, cs.Name+"/"+reason)
+			}
+		}
+	}
+
+	var fresh []podIncident
+	for _, i := range incidents {
+		k := i.container + "/" + i.reason
+		if !notified[k] {
+			notified[k] = true
+			fresh = append(fresh, i)
+		}
+	}
+	// terminated pods don't restart, their incidents are no longer needed
+	if pod.Status.Phase == api_v1.PodSucceeded || pod.Status.Phase == api_v1.PodFailed {
+		delete(a.incidents, string(pod.UID))
+	}
+	return fresh
+}
+
+// forget drops the state of a deleted pod
+func (a *podAnalyzer) forget(pod *api_v1.Pod) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.incidents, string(pod.UID))
+	a.stopPending(string(pod.UID))
+}
+
+// stopPending stops waiting for the timeout of a pending pod
+func (a *podAnalyzer) stopPending(uid string) {
+	if timer := a.pending[uid]; timer != nil {
+		timer.Stop()
+	}
+	delete(a.pending, uid)
+}
+
+// forgetDeletedPod drops the state of a deleted pod, unwrapping the tombstones of the deletes missed by the watch
+func forgetDeletedPod(obj interface{}) {
+	if podHealth == nil {
+		return
+	}
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	if pod, ok := obj.(*api_v1.Pod); ok {
+		podHealth.forget(pod)
+	}
+}
+
+// watchPending notifies the pod if it is still pending after the timeout from its creation
+func (a *podAnalyzer) watchPending(c *Controller, newEvent Event, o overrides, pod *api_v1.Pod) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	uid := string(pod.UID)
+	if pod.Status.Phase != api_v1.PodPending {
+		a.stopPending(uid)
+		return
+	}
+	if _, ok := a.pending[uid]; ok {
+		return
+	}
+
+	wait := time.Until(pod.CreationTimestamp.Add(a.pendingTimeout))
+	a.pending[uid] = time.AfterFunc(wait, func() {
+		a.mu.Lock()
+		delete(a.pending, uid)
+		a.mu.Unlock()
+
+		obj, exists, err := c.informer.GetIndexer().GetByKey(pod.Namespace + "/" + pod.Name)
+		if err != nil || !exists {
+			return
+		}
+		current, ok := obj.(*api_v1.Pod)
+		if !ok || current.UID != pod.UID || current.Status.Phase != api_v1.PodPending {
+			return
+		}
+		c.notifyPodIncident(newEvent, o, podIncident{reason: ReasonPendingTimeout, details: pendingDetails(current, a.pendingTimeout)})
+	})
+}
+
+// seedPending records a pod pending before the start without waiting for its timeout
+func (a *podAnalyzer) seedPending(pod *api_v1.Pod) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.pending[string(pod.UID)]; !ok && pod.Status.Phase == api_v1.PodPending {
+		a.pending[string(pod.UID)] = nil
+	}
+}
+
+// pendingDetails explains why a pod is still pending, from its scheduling condition or waiting containers
+func pendingDetails(pod *api_v1.Pod, timeout time.Duration) string {
+	details := fmt.Sprintf("pending for more than %s", timeout)
+	for _, c := range pod.Status.Conditions {
+		if c.Type == api_v1.PodScheduled && c.Status == api_v1.ConditionFalse {
+			return fmt.Sprintf("%s, %s: %s", details, c.Reason, c.Message)
+		}
+	}
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.State.Waiting != nil && cs.State.Waiting.Reason != "" {
+			return fmt.Sprintf("%s, container %s %s", details, cs.Name, cs.State.Waiting.Reason)
+		}
+	}
+	return details
+}
+
+// analyzePod notifies the failures of a created or updated pod
+func (c *Controller) analyzePod(newEvent Event, o overrides) {
+	pod, ok := newEvent.obj.(*api_v1.Pod)
+	if !ok {
+		return
+	}
+	old, _ := newEvent.oldObj.(*api_v1.Pod)
+
+	// the failures of the pods of the initial list happened before the start, they are only recorded
+	if newEvent.eventType == "create" && pod.CreationTimestamp.Time.Before(serverStartTime) {
+		podHealth.analyze(old, pod)
+		podHealth.seedPending(pod)
+		return
+	}
+	for _, i := range podHealth.analyze(old, pod) {
+		c.notifyPodIncident(newEvent, o, i)
+	}
+	podHealth.watchPending(c, newEvent, o, pod)
+}
+
+// notifyPodIncident sends a pod failure, its severity set by the severity rules matching its reason
+func (c *Controller) notifyPodIncident(newEvent Event, o overrides, i podIncident) {
+	logrus.Infof("Pod %s/%s %s: %s", newEvent.namespace, newEvent.key, i.reason, i.details)
+	c.notifyReason(newEvent, o, i.reason, i.details, podIncidentSeverities[i.reason])
+}
@@ -0,0 +1,209 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers"
+	"github.com/stretchr/testify/assert"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func podWith(statuses ...api_v1.ContainerStatus) *api_v1.Pod {
+	return &api_v1.Pod{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "api-x2", Namespace: "default", UID: "uid-1"},
+		Status:     api_v1.PodStatus{Phase: api_v1.PodRunning, ContainerStatuses: statuses},
+	}
+}
+
+func crashed(restarts int32, reason string, exitCode int32, ran time.Duration) api_v1.ContainerStatus {
+	finished := time.Date(2023, 11, 1, 10, 0, 0, 0, time.UTC)
+	return api_v1.ContainerStatus{
+		Name:         "api",
+		RestartCount: restarts,
+		State:        api_v1.ContainerState{Waiting: &api_v1.ContainerStateWaiting{Reason: "CrashLoopBackOff", Message: "back-off 10s"}},
+		LastTerminationState: api_v1.ContainerState{Terminated: &api_v1.ContainerStateTerminated{
+			Reason:     reason,
+			ExitCode:   exitCode,
+			Message:    "out of memory",
+			StartedAt:  meta_v1.NewTime(finished.Add(-ran)),
+			FinishedAt: meta_v1.NewTime(finished),
+		}},
+	}
+}
+
+func reasons(incidents []podIncident) []string {
+	var r []string
+	for _, i := range incidents {
+		r = append(r, i.reason)
+	}
+	return r
+}
+
+func TestPodAnalyzer(t *testing.T) {
+	a := newPodAnalyzer(config.PodHealth{})
+	running := podWith(api_v1.ContainerStatus{Name: "api", Ready: true, State: api_v1.ContainerState{Running: &api_v1.ContainerStateRunning{}}})
+
+	oom := podWith(crashed(1, "OOMKilled", 137, time.Second))
+	incidents := a.analyze(running, oom)
+	assert.Equal(t, []string{ReasonCrashLoopBackOff, ReasonOOMKilled}, reasons(incidents))
+	assert.Equal(t, "container api, restarts 1, back-off 10s, last termination: OOMKilled (exit code 137) out of memory", incidents[0].details)
+
+	// the same incident isn't notified again while the container crash loops
+	assert.Empty(t, a.analyze(oom, podWith(crashed(2, "OOMKilled", 137, time.Second))))
+
+	// a crash after running long enough is a new incident, the CrashLoopBackOff describing the failure of the container
+	assert.Equal(t, []string{ReasonCrashLoopBackOff}, reasons(a.analyze(oom, podWith(crashed(3, "Error", 1, time.Hour)))))
+	failed := podWith(crashed(3, "Error", 1, time.Hour))
+	failed.Status.ContainerStatuses[0].State = api_v1.ContainerState{Running: &api_v1.ContainerStateRunning{}}
+	failed.UID = "uid-2"
+	assert.Equal(t, []string{ReasonContainerFailed}, reasons(a.analyze(running, failed)))
+
+	pull := podWith(api_v1.ContainerStatus{Name: "sidecar", State: api_v1.ContainerState{Waiting: &api_v1.ContainerStateWaiting{Reason: "ErrImagePull", Message: "not found"}}})
+	incidents = a.analyze(nil, pull)
+	assert.Equal(t, []string{ReasonImagePullBackOff}, reasons(incidents))
+	assert.Equal(t, "container sidecar, restarts 0, ErrImagePull: not found", incidents[0].details)
+	pull.Status.ContainerStatuses[0].State.Waiting.Reason = "ImagePullBackOff"
+	assert.Empty(t, a.analyze(nil, pull))
+
+	evicted := podWith()
+	evicted.Status.Phase = api_v1.PodFailed
+	evicted.Status.Reason = "Evicted"
+	evicted.Status.Message = "The node was low on resource: memory."
+	incidents = a.analyze(running, evicted)
+	assert.Equal(t, []string{ReasonEvicted}, reasons(incidents))
+	assert.Equal(t, "The node was low on resource: memory.", incidents[0].details)
+
+	// completed containers aren't failures
+	completed := podWith(api_v1.ContainerStatus{Name: "job", State: api_v1.ContainerState{Terminated: &api_v1.ContainerStateTerminated{Reason: "Completed"}}})
+	assert.Empty(t, a.analyze(nil, completed))
+
+	a.forget(evicted)
+	assert.NotContains(t, a.incidents, "uid-1")
+}
+
+func TestPodPendingTimeout(t *testing.T) {
+	slack := &recordingHandler{}
+	informer := cache.NewSharedIndexInformer(&cache.ListWatch{}, &api_v1.Pod{}, 0, cache.Indexers{})
+	c := &Controller{eventHandlers: map[string]handlers.Handler{"slack": slack}, informer: informer}
+
+	pending := &api_v1.Pod{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "api-x2", Namespace: "default", UID: "uid-1", CreationTimestamp: meta_v1.Now()},
+		Status: api_v1.PodStatus{
+			Phase:      api_v1.PodPending,
+			Conditions: []api_v1.PodCondition{{Type: api_v1.PodScheduled, Status: api_v1.ConditionFalse, Reason: "Unschedulable", Message: "0/3 nodes are available"}},
+		},
+	}
+	assert.NoError(t, informer.GetIndexer().Add(pending))
+
+	podHealth = newPodAnalyzer(config.PodHealth{})
+	podHealth.pendingTimeout = 50 * time.Millisecond
+	defer func() { podHealth = nil }()
+
+	newEvent := Event{key: "api-x2", namespace: "default", eventType: "create", resourceType: "Pod", apiVersion: V1, obj: pending}
+	c.analyzePod(newEvent, overrides{})
+	c.analyzePod(newEvent, overrides{})
+
+	assert.Eventually(t, func() bool { return len(slack.received()) == 1 }, time.Second, 10*time.Millisecond)
+	e := slack.received()[0]
+	assert.Equal(t, ReasonPendingTimeout, e.Reason)
+	assert.Equal(t, "Warning", e.Status)
+	assert.Equal(t, "pending for more than 50ms, Unschedulable: 0/3 nodes are available", e.Details)
+
+	// scheduled pods are no longer watched
+	running := pending.DeepCopy()
+	running.UID = "uid-2"
+	c.analyzePod(Event{key: "api-x2", namespace: "default", eventType: "create", resourceType: "Pod", obj: running}, overrides{})
+	running.Status.Phase = api_v1.PodRunning
+	c.analyzePod(Event{key: "api-x2", namespace: "default", eventType: "update", resourceType: "Pod", obj: running}, overrides{})
+	time.Sleep(100 * time.Millisecond)
+	assert.Len(t, slack.received(), 1)
+}
+
+func TestPodAnalyzerStart(t *testing.T) {
+	slack := &recordingHandler{}
+	informer := cache.NewSharedIndexInformer(&cache.ListWatch{}, &api_v1.Pod{}, 0, cache.Indexers{})
+	c := &Controller{eventHandlers: map[string]handlers.Handler{"slack": slack}, informer: informer}
+
+	podHealth = newPodAnalyzer(config.PodHealth{})
+	podHealth.pendingTimeout = 50 * time.Millisecond
+	defer func() { podHealth = nil }()
+	started := serverStartTime
+	serverStartTime = time.Now()
+	defer func() { serverStartTime = started }()
+
+	// the pods of the initial list, crash looping or pending before the start
+	crashing := podWith(crashed(4, "Error", 1, time.Second))
+	crashing.CreationTimestamp = meta_v1.NewTime(serverStartTime.Add(-time.Hour))
+	pending := &api_v1.Pod{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "web-x2", Namespace: "default", UID: "uid-2", CreationTimestamp: crashing.CreationTimestamp},
+		Status:     api_v1.PodStatus{Phase: api_v1.PodPending},
+	}
+	assert.NoError(t, informer.GetIndexer().Add(pending))
+	c.analyzePod(Event{key: "api-x2", namespace: "default", eventType: "create", resourceType: "Pod", obj: crashing}, overrides{})
+	c.analyzePod(Event{key: "web-x2", namespace: "default", eventType: "create", resourceType: "Pod", obj: pending}, overrides{})
+
+	// their failures are recorded, not notified again by the following updates
+	c.analyzePod(Event{key: "api-x2", namespace: "default", eventType: "update", resourceType: "Pod", oldObj: crashing, obj: podWith(crashed(5, "Error", 1, time.Second))}, overrides{})
+	c.analyzePod(Event{key: "web-x2", namespace: "default", eventType: "update", resourceType: "Pod", oldObj: pending, obj: pending}, overrides{})
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, slack.received())
+
+	// until the pending pod is scheduled
+	running := pending.DeepCopy()
+	running.Status.Phase = api_v1.PodRunning
+	c.analyzePod(Event{key: "web-x2", namespace: "default", eventType: "update", resourceType: "Pod", oldObj: pending, obj: running}, overrides{})
+	assert.NotContains(t, podHealth.pending, "uid-2")
+}
+
+func TestPodDeleteForgets(t *testing.T) {
+	podHealth = newPodAnalyzer(config.PodHealth{})
+	defer func() { podHealth = nil }()
+
+	pod := podWith(crashed(1, "OOMKilled", 137, time.Second))
+	client := fake.NewSimpleClientset(pod)
+	informer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+				return client.CoreV1().Pods("").List(context.Background(), options)
+			},
+			WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+				return client.CoreV1().Pods("").Watch(context.Background(), options)
+			},
+		},
+		&api_v1.Pod{}, 0, cache.Indexers{},
+	)
+	// the deletes aren't notified
+	newResourceController(client, map[string]handlers.Handler{}, informer, "Pod", V1, config.ResourceConfig{Enabled: true, IncludeEvenTypes: []string{"update"}})
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go informer.Run(stopCh)
+	assert.True(t, cache.WaitForCacheSync(stopCh, informer.HasSynced))
+
+	assert.NotEmpty(t, podHealth.analyze(nil, pod))
+	assert.NoError(t, client.CoreV1().Pods("default").Delete(context.Background(), pod.Name, meta_v1.DeleteOptions{}))
+	assert.Eventually(t, func() bool {
+		podHealth.mu.Lock()
+		defer podHealth.mu.Unlock()
+		return len(podHealth.incidents) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	// the tombstones of the deletes missed by the watch
+	podHealth.analyze(nil, pod)
+	forgetDeletedPod(cache.DeletedFinalStateUnknown{Key: "default/api-x2", Obj: pod})
+	assert.Empty(t, podHealth.incidents)
+
+	// terminated pods
+	failed := podWith(crashed(1, "Error", 1, time.Second))
+	failed.Status.Phase = api_v1.PodFailed
+	assert.NotEmpty(t, podHealth.analyze(nil, failed))
+	assert.Empty(t, podHealth.incidents)
+}
@@ -0,0 +1,202 @@
+package controller
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+	apps_v1 "k8s.io/api/apps/v1"
+	api_v1 "k8s.io/api/core/v1"
+)
+
+var rolloutDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "statemonitor_rollout_duration_seconds",
+	Help:    "The duration of the rollouts of deployments, statefulsets and daemonsets",
+	Buckets: prometheus.ExponentialBuckets(5, 2, 10),
+},
+	[]string{"Kind", "Namespace", "Result"})
+
+// rollouts follows the rollouts after spec updates, nil when disabled
+var rollouts *rolloutTracker
+
+// rolloutStatus is the progress of the rollout of an object's generation
+type rolloutStatus struct {
+	generation int64
+	complete   bool
+	// failure is the reason of a failed rollout
+	failure string
+}
+
+// getRolloutStatus reads the rollout progress of deployments, statefulsets and daemonsets, false for other objects
+func getRolloutStatus(obj interface{}) (rolloutStatus, bool) {
+	switch o := obj.(type) {
+	case *apps_v1.Deployment:
+		s := rolloutStatus{generation: o.Generation}
+		if o.Status.ObservedGeneration < o.Generation {
+			return s, true
+		}
+		for _, c := range o.Status.Conditions {
+			if c.Type == apps_v1.DeploymentProgressing && c.Status == api_v1.ConditionFalse && c.Reason == "ProgressDeadlineExceeded" {
+				s.failure = c.Reason
+				return s, true
+			}
+		}
+		replicas := int32(1)
+		if o.Spec.Replicas != nil {
+			replicas = *o.Spec.Replicas
+		}
+		s.complete = o.Status.UpdatedReplicas == replicas && o.Status.Replicas == replicas && o.Status.AvailableReplicas == replicas
+		return s, true
+	case *apps_v1.StatefulSet:
+		s := rolloutStatus{generation: o.Generation}
+		// pods of OnDelete statefulsets are only updated when deleted, there is no rollout to follow
+		if o.Spec.UpdateStrategy.Type == apps_v1.OnDeleteStatefulSetStrategyType {
+			return s, false
+		}
+		if o.Status.ObservedGeneration < o.Generation {
+			return s, true
+		}
+		replicas := int32(1)
+		if o.Spec.Replicas != nil {
+			replicas = *o.Spec.Replicas
+		}
+		s.complete = o.Status.UpdatedReplicas == replicas && o.Status.AvailableReplicas == replicas && o.Status.CurrentRevision == o.Status.UpdateRevision
+		return s, true
+	case *apps_v1.DaemonSet:
+		s := rolloutStatus{generation: o.Generation}
+		if o.Spec.UpdateStrategy.Type == apps_v1.OnDeleteDaemonSetStrategyType {
+			return s, false
+		}
+		if o.Status.ObservedGeneration < o.Generation {
+			return s, true
+		}
+		desired := o.Status.DesiredNumberScheduled
+		s.complete = o.Status.UpdatedNumberScheduled == desired && o.Status.NumberAvailable == desired
+		return s, true
+	}
+	return rolloutStatus{}, false
+}
+
+// rollout is a rollout in progress
+type rollout struct {
+	generation int64
+	started    time.Time
+	timer      *time.Timer
+}
+
+// rolloutTracker follows the rollouts of the objects after their spec updates
+// and sends a follow-up event when they complete or fail
+type rolloutTracker struct {
+	timeout  time.Duration
+	mu       sync.Mutex
+	rollouts map[string]*rollout
+	now      func() time.Time
+}
+
+func newRolloutTracker(conf config.Rollout) *rolloutTracker {
+	return &rolloutTracker{
+		timeout:  time.Duration(conf.TimeoutSeconds) * time.Second,
+		rollouts: map[string]*rollout{},
+		now:      time.Now,
+	}
+}
+
+func rolloutKey(newEvent Event) string {
+	return newEvent.resourceType + "/" + newEvent.namespace + "/" + newEvent.key
+}
+
+// update starts following the rollout of a new generation of the object, and notifies the end of the followed one
+func (t *rolloutTracker) update(c *Controller, newEvent Event, routeName string) {
+	status, ok := getRolloutStatus(newEvent.obj)
+	if !ok {
+		return
+	}
+	old, ok := getRolloutStatus(newEvent.oldObj)
+	key := rolloutKey(newEvent)
+
+	t.mu.Lock()
+	r := t.rollouts[key]
+	if ok && status.generation > old.generation && (r == nil || r.generation < status.generation) {
+		// a spec update supersedes the rollout in progress
+		if r != nil && r.timer != nil {
+			r.timer.Stop()
+		}
+		r = &rollout{generation: status.generation, started: t.now()}
+		if t.timeout > 0 {
+			r.timer = time.AfterFunc(t.timeout, func() { t.expire(c, newEvent, routeName, r) })
+		}
+		t.rollouts[key] = r
+	}
+	if r == nil || status.generation != r.generation || (status.failure == "" && !status.complete) {
+		t.mu.Unlock()
+		return
+	}
+	e := t.finish(newEvent, key, r, status.failure)
+	t.mu.Unlock()
+
+	c.notifyFollowUp(e, newEvent, routeName)
+}
+
+// expire fails the rollout when it didn't complete within the timeout
+func (t *rolloutTracker) expire(c *Controller, newEvent Event, routeName string, r *rollout) {
+	t.mu.Lock()
+	key := rolloutKey(newEvent)
+	if t.rollouts[key] != r {
+		t.mu.Unlock()
+		return
+	}
+	e := t.finish(newEvent, key, r, "Timeout")
+	t.mu.Unlock()
+
+	c.notifyFollowUp(e, newEvent, routeName)
+}
+
+// stop forgets the rollout of a deleted object
+func (t *rolloutTracker) stop(newEvent Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := rolloutKey(newEvent)
+	if r, ok := t.rollouts[key]; ok {
+		if r.timer != nil {
+			r.timer.Stop()
+		}
+		delete(t.rollouts, key)
+	}
+}
+
+// finish records the duration of the rollout and returns the event of its result, an empty failure being a completion
+func (t *rolloutTracker) finish(newEvent Event, key string, r *rollout, failure string) event.StatemonitorEvent {
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+	delete(t.rollouts, key)
+
+	duration := t.now().Sub(r.started)
+	e := event.StatemonitorEvent{
+		Name:       newEvent.key,
+		Namespace:  newEvent.namespace,
+		Kind:       newEvent.resourceType,
+		ApiVersion: newEvent.apiVersion,
+	}
+	result := "complete"
+	if failure == "" {
+		e.Status = "Normal"
+		e.Reason = "RolloutComplete"
+		e.Details = fmt.Sprintf("rollout complete in %s", duration.Round(time.Second))
+	} else {
+		result = "failed"
+		e.Status = "Danger"
+		e.Reason = "RolloutFailed"
+		e.Details = fmt.Sprintf("rollout failed: %s", failure)
+	}
+
+	rolloutDuration.WithLabelValues(newEvent.resourceType, newEvent.namespace, result).Observe(duration.Seconds())
+	logrus.Infof("Rollout of %s %s", key, e.Details)
+	return e
+}
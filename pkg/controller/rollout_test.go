@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers"
+	"github.com/stretchr/testify/assert"
+	apps_v1 "k8s.io/api/apps/v1"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func deployment(generation int64, observed int64, updated int32, available int32) *apps_v1.Deployment {
+	replicas := int32(3)
+	return &apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{Name: "api", Namespace: "default", Generation: generation},
+		Spec:       apps_v1.DeploymentSpec{Replicas: &replicas},
+		Status: apps_v1.DeploymentStatus{
+			ObservedGeneration: observed,
+			Replicas:           3,
+			UpdatedReplicas:    updated,
+			AvailableReplicas:  available,
+		},
+	}
+}
+
+func deploymentUpdate(old *apps_v1.Deployment, new *apps_v1.Deployment) Event {
+	return Event{key: "api", namespace: "default", eventType: "update", resourceType: "Deployment", apiVersion: APPS_V1, oldObj: old, obj: new}
+}
+
+func TestGetRolloutStatus(t *testing.T) {
+	failed := deployment(2, 2, 1, 2)
+	failed.Status.Conditions = []apps_v1.DeploymentCondition{
+		{Type: apps_v1.DeploymentProgressing, Status: api_v1.ConditionFalse, Reason: "ProgressDeadlineExceeded"},
+	}
+	onDelete := &apps_v1.StatefulSet{Spec: apps_v1.StatefulSetSpec{UpdateStrategy: apps_v1.StatefulSetUpdateStrategy{Type: apps_v1.OnDeleteStatefulSetStrategyType}}}
+	statefulSet := &apps_v1.StatefulSet{
+		ObjectMeta: meta_v1.ObjectMeta{Generation: 4},
+		Status:     apps_v1.StatefulSetStatus{ObservedGeneration: 4, UpdatedReplicas: 1, AvailableReplicas: 1, CurrentRevision: "a", UpdateRevision: "a"},
+	}
+	daemonSet := &apps_v1.DaemonSet{
+		ObjectMeta: meta_v1.ObjectMeta{Generation: 2},
+		Status:     apps_v1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 2},
+	}
+
+	var Tests = []struct {
+		name     string
+		obj      interface{}
+		ok       bool
+		complete bool
+		failure  string
+	}{
+		{"not observed", deployment(2, 1, 3, 3), true, false, ""},
+		{"in progress", deployment(2, 2, 2, 3), true, false, ""},
+		{"complete", deployment(2, 2, 3, 3), true, true, ""},
+		{"deadline exceeded", failed, true, false, "ProgressDeadlineExceeded"},
+		{"statefulset complete", statefulSet, true, true, ""},
+		{"statefulset on delete", onDelete, false, false, ""},
+		{"daemonset unavailable", daemonSet, true, false, ""},
+		{"configmap", &api_v1.ConfigMap{}, false, false, ""},
+	}
+
+	for _, tt := range Tests {
+		s, ok := getRolloutStatus(tt.obj)
+		assert.Equal(t, tt.ok, ok, tt.name)
+		assert.Equal(t, tt.complete, s.complete, tt.name)
+		assert.Equal(t, tt.failure, s.failure, tt.name)
+	}
+}
+
+func TestRolloutTracker(t *testing.T) {
+	slack := &recordingHandler{}
+	c := &Controller{eventHandlers: map[string]handlers.Handler{"slack": slack}}
+	tracker := newRolloutTracker(config.Rollout{})
+	now := time.Date(2023, 11, 1, 10, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+
+	// status updates without a spec change aren't rollouts
+	tracker.update(c, deploymentUpdate(deployment(1, 1, 2, 2), deployment(1, 1, 3, 3)), "")
+	assert.Len(t, slack.received(), 0)
+
+	tracker.update(c, deploymentUpdate(deployment(1, 1, 3, 3), deployment(2, 1, 3, 3)), "")
+	now = now.Add(time.Minute)
+	tracker.update(c, deploymentUpdate(deployment(2, 1, 3, 3), deployment(2, 2, 1, 3)), "")
+	assert.Len(t, slack.received(), 0)
+
+	now = now.Add(73 * time.Second)
+	tracker.update(c, deploymentUpdate(deployment(2, 2, 1, 3), deployment(2, 2, 3, 3)), "")
+	// the rollout is over, further updates don't notify again
+	tracker.update(c, deploymentUpdate(deployment(2, 2, 3, 3), deployment(2, 2, 3, 3)), "")
+
+	events := slack.received()
+	assert.Len(t, events, 1)
+	assert.Equal(t, "RolloutComplete", events[0].Reason)
+	assert.Equal(t, "Normal", events[0].Status)
+	assert.Equal(t, "rollout complete in 2m13s", events[0].Details)
+
+	failed := deployment(3, 3, 1, 2)
+	failed.Status.Conditions = []apps_v1.DeploymentCondition{
+		{Type: apps_v1.DeploymentProgressing, Status: api_v1.ConditionFalse, Reason: "ProgressDeadlineExceeded"},
+	}
+	tracker.update(c, deploymentUpdate(deployment(2, 2, 3, 3), deployment(3, 2, 3, 3)), "")
+	tracker.update(c, deploymentUpdate(deployment(3, 2, 3, 3), failed), "")
+
+	events = slack.received()
+	assert.Len(t, events, 2)
+	assert.Equal(t, "RolloutFailed", events[1].Reason)
+	assert.Equal(t, "Danger", events[1].Status)
+	assert.Equal(t, "rollout failed: ProgressDeadlineExceeded", events[1].Details)
+}
+
+func TestRolloutTrackerTimeout(t *testing.T) {
+	slack := &recordingHandler{}
+	c := &Controller{eventHandlers: map[string]handlers.Handler{"slack": slack}}
+	tracker := newRolloutTracker(config.Rollout{})
+	tracker.timeout = 50 * time.Millisecond
+
+	tracker.update(c, deploymentUpdate(deployment(1, 1, 3, 3), deployment(2, 1, 3, 3)), "")
+	assert.Eventually(t, func() bool { return len(slack.received()) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "rollout failed: Timeout", slack.received()[0].Details)
+
+	// deleted objects are no longer followed
+	tracker.update(c, deploymentUpdate(deployment(2, 1, 3, 3), deployment(3, 1, 3, 3)), "")
+	tracker.stop(deploymentUpdate(nil, nil))
+	time.Sleep(100 * time.Millisecond)
+	assert.Len(t, slack.received(), 1)
+}
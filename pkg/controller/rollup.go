@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/sirupsen/logrus"
+)
+
+const defaultRollupWindow = 10 * time.Second
+
+// rollups groups the events of the owned objects by top-level owner, nil when disabled
+var rollups *rollup
+
+// dropWatchedChildren drops the events of the objects whose top-level owner is watched
+var dropWatchedChildren bool
+
+// rollupGroup holds the events of an owner and of its children within the window
+type rollupGroup struct {
+	owner    owner
+	c        *Controller
+	route    string
+	event    *event.StatemonitorEvent
+	children []string
+	status   string
+	// timer flushes the group at the end of the window
+	timer *time.Timer
+}
+
+// rollup sends one notification per owner for the events of the owner and of its children within the window
+type rollup struct {
+	window time.Duration
+	mu     sync.Mutex
+	groups map[string]*rollupGroup
+	// closed once the pending groups were sent on termination, the events being no longer grouped
+	closed bool
+}
+
+func newRollup(conf config.Rollup) *rollup {
+	window := time.Duration(conf.WindowSeconds) * time.Second
+	if window <= 0 {
+		window = defaultRollupWindow
+	}
+	return &rollup{window: window, groups: map[string]*rollupGroup{}}
+}
+
+// add puts the event in the group of its top-level owner, it returns false if the event isn't part of a rollup.
+// Events of the owners are grouped with the events of their children, owners being the workloads able to own objects
+func (r *rollup) add(c *Controller, e event.StatemonitorEvent, o owner, child bool, routeName string) bool {
+	if !child {
+		if _, ok := ownerResources[e.Kind]; !ok {
+			return false
+		}
+		o = owner{kind: e.Kind, apiVersion: e.ApiVersion, namespace: e.Namespace, name: e.Name}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return false
+	}
+
+	// the apiVersion of the ownerReferences may differ from the watched one
+	key := o.String()
+	g, ok := r.groups[key]
+	if !ok {
+		g = &rollupGroup{owner: o, c: c, route: routeName}
+		r.groups[key] = g
+		g.timer = time.AfterFunc(r.window, func() { r.flush(key) })
+	}
+	if child {
+		g.children = append(g.children, fmt.Sprintf("%s %s %s", e.Kind, e.Name, e.Reason))
+	} else {
+		// the owner's own event carries the diff and route of the rollup
+		g.event, g.c, g.route = &e, c, routeName
+	}
+	g.status = maxStatus(g.status, e.Status)
+	return true
+}
+
+// flush sends the notification of the owner at the end of the window
+func (r *rollup) flush(key string) {
+	r.mu.Lock()
+	g, ok := r.groups[key]
+	delete(r.groups, key)
+	r.mu.Unlock()
+
+	// the group may have been sent on termination
+	if !ok {
+		return
+	}
+	e := g.notification()
+	logrus.Infof("Rollup of %s with %d children", key, len(g.children))
+	in := filterInput(e, Event{})
+	g.c.dispatch(e, in, g.route)
+}
+
+// flushAll sends the notifications of the pending groups before terminating, the windows still open being closed
+func (r *rollup) flushAll() {
+	r.mu.Lock()
+	r.closed = true
+	keys := make([]string, 0, len(r.groups))
+	for key, g := range r.groups {
+		g.timer.Stop()
+		keys = append(keys, key)
+	}
+	r.mu.Unlock()
+
+	for _, key := range keys {
+		r.flush(key)
+	}
+}
+
+// notification is the owner's event with the children of the group, a ChildrenChanged event when the owner didn't change
+func (g *rollupGroup) notification() event.StatemonitorEvent {
+	e := event.StatemonitorEvent{
+		Name:       g.owner.name,
+		Namespace:  g.owner.namespace,
+		Kind:       g.owner.kind,
+		ApiVersion: g.owner.apiVersion,
+		Reason:     "ChildrenChanged",
+	}
+	if g.event != nil {
+		e = *g.event
+	}
+	e.Status = g.status
+	e.Children = g.children
+	return e
+}
+
+// maxStatus returns the most severe of two statuses
+func maxStatus(a string, b string) string {
+	rank := map[string]int{"": 0, "Normal": 1, "Warning": 2, "Danger": 3}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+// rollupEvent drops or groups the events of the owned objects, it returns true if the event must not be dispatched now
+func (c *Controller) rollupEvent(e event.StatemonitorEvent, newEvent Event, routeName string) bool {
+	if !dropWatchedChildren && rollups == nil {
+		return false
+	}
+	o, child := topOwner(newEvent.obj)
+	if dropWatchedChildren && child && watchedKind(o.kind) {
+		logrus.Debugf("Skipping %v %s (owner %s is watched)", newEvent.resourceType, newEvent.key, o)
+		return true
+	}
+	return rollups != nil && rollups.add(c, e, o, child, routeName)
+}
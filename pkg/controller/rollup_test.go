@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers"
+	"github.com/stretchr/testify/assert"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func owned(kind string, name string, ownerKind string, ownerName string) meta_v1.ObjectMeta {
+	isController := true
+	return meta_v1.ObjectMeta{
+		Name:      name,
+		Namespace: "default",
+		OwnerReferences: []meta_v1.OwnerReference{
+			{Kind: ownerKind, APIVersion: "apps/v1", Name: ownerName, Controller: &isController},
+		},
+	}
+}
+
+func resetOwners() {
+	ownerStores = map[string][]cache.Store{}
+	ownerClient = nil
+	ownerCache = map[owner]cachedOwner{}
+	rollups = nil
+	dropWatchedChildren = false
+}
+
+func TestTopOwner(t *testing.T) {
+	defer resetOwners()
+
+	// the ReplicaSet is watched, the Deployment is looked up with the metadata client
+	replicaSets := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	rs := &meta_v1.PartialObjectMetadata{ObjectMeta: owned("ReplicaSet", "api-7d9", "Deployment", "api")}
+	assert.NoError(t, replicaSets.Add(rs))
+	registerOwnerStore("ReplicaSet", replicaSets)
+
+	scheme := metadatafake.NewTestScheme()
+	assert.NoError(t, meta_v1.AddMetaToScheme(scheme))
+	deployment := &meta_v1.PartialObjectMetadata{
+		TypeMeta:   meta_v1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: meta_v1.ObjectMeta{Name: "api", Namespace: "default"},
+	}
+	ownerClient = metadatafake.NewSimpleMetadataClient(scheme, deployment)
+
+	pod := &api_v1.Pod{ObjectMeta: owned("Pod", "api-7d9-x2", "ReplicaSet", "api-7d9")}
+	o, ok := topOwner(pod)
+	assert.True(t, ok)
+	assert.Equal(t, owner{kind: "Deployment", apiVersion: "apps/v1", namespace: "default", name: "api"}, o)
+	assert.True(t, watchedKind("ReplicaSet"))
+	assert.False(t, watchedKind("Deployment"))
+
+	// owners that can't be looked up end the chain
+	job := &meta_v1.PartialObjectMetadata{ObjectMeta: owned("Job", "backup-28", "CronJob", "backup")}
+	o, ok = topOwner(job)
+	assert.True(t, ok)
+	assert.Equal(t, "CronJob", o.kind)
+
+	_, ok = topOwner(&api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Name: "standalone"}})
+	assert.False(t, ok)
+	_, ok = topOwner(nil)
+	assert.False(t, ok)
+}
+
+func TestOwnerCache(t *testing.T) {
+	defer resetOwners()
+
+	scheme := metadatafake.NewTestScheme()
+	assert.NoError(t, meta_v1.AddMetaToScheme(scheme))
+	deployment := &meta_v1.PartialObjectMetadata{
+		TypeMeta:   meta_v1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: meta_v1.ObjectMeta{Name: "api", Namespace: "default"},
+	}
+	client := metadatafake.NewSimpleMetadataClient(scheme, deployment)
+	ownerClient = client
+
+	// the owners and the missing owners are looked up once
+	for i := 0; i < 3; i++ {
+		o, ok := topOwner(&meta_v1.PartialObjectMetadata{ObjectMeta: owned("ReplicaSet", "api-7d9", "Deployment", "api")})
+		assert.True(t, ok)
+		assert.Equal(t, "api", o.name)
+		o, ok = topOwner(&meta_v1.PartialObjectMetadata{ObjectMeta: owned("ReplicaSet", "web-5f4", "Deployment", "web")})
+		assert.True(t, ok)
+		assert.Equal(t, "web", o.name)
+	}
+	assert.Len(t, client.Actions(), 2)
+
+	// until they expire
+	ownerCacheTTL = -time.Second
+	defer func() { ownerCacheTTL = time.Minute }()
+	ownerCache = map[owner]cachedOwner{}
+	topOwner(&meta_v1.PartialObjectMetadata{ObjectMeta: owned("ReplicaSet", "api-7d9", "Deployment", "api")})
+	topOwner(&meta_v1.PartialObjectMetadata{ObjectMeta: owned("ReplicaSet", "api-7d9", "Deployment", "api")})
+	assert.Len(t, client.Actions(), 4)
+}
+
+func TestRollup(t *testing.T) {
+	defer resetOwners()
+
+	replicaSets := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	assert.NoError(t, replicaSets.Add(&meta_v1.PartialObjectMetadata{ObjectMeta: owned("ReplicaSet", "api-7d9", "Deployment", "api")}))
+	registerOwnerStore("ReplicaSet", replicaSets)
+
+	slack := &recordingHandler{}
+	c := &Controller{eventHandlers: map[string]handlers.Handler{"slack": slack}}
+	rollups = newRollup(config.Rollup{Enabled: true})
+	rollups.window = 50 * time.Millisecond
+
+	pod := func(name string) Event {
+		return Event{eventType: "create", resourceType: "Pod", obj: &api_v1.Pod{ObjectMeta: owned("Pod", name, "ReplicaSet", "api-7d9")}}
+	}
+	c.notify(event.StatemonitorEvent{Kind: "Deployment", Name: "api", Namespace: "default", Reason: "Updated", Status: "Warning", Diff: "[]"},
+		Event{eventType: "update", resourceType: "Deployment", obj: &api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Name: "api", Namespace: "default"}}}, "")
+	c.notify(event.StatemonitorEvent{Kind: "Pod", Name: "api-7d9-a", Namespace: "default", Reason: "Created", Status: "Normal"}, pod("api-7d9-a"), "")
+	c.notify(event.StatemonitorEvent{Kind: "Pod", Name: "api-7d9-b", Namespace: "default", Reason: "Deleted", Status: "Danger"}, pod("api-7d9-b"), "")
+	// not owned by a workload
+	c.notify(event.StatemonitorEvent{Kind: "ConfigMap", Name: "settings", Namespace: "default", Reason: "Updated"}, Event{eventType: "update", resourceType: "ConfigMap"}, "")
+
+	assert.Len(t, slack.received(), 1)
+	assert.Eventually(t, func() bool { return len(slack.received()) == 2 }, time.Second, 10*time.Millisecond)
+
+	e := slack.received()[1]
+	assert.Equal(t, "Deployment", e.Kind)
+	assert.Equal(t, "api", e.Name)
+	assert.Equal(t, "Updated", e.Reason)
+	assert.Equal(t, "Danger", e.Status)
+	assert.Equal(t, "[]", e.Diff)
+	assert.Equal(t, []string{"Pod api-7d9-a Created", "Pod api-7d9-b Deleted"}, e.Children)
+}
+
+func TestRollupFlushAll(t *testing.T) {
+	defer resetOwners()
+
+	slack := &recordingHandler{}
+	c := &Controller{eventHandlers: map[string]handlers.Handler{"slack": slack}}
+	rollups = newRollup(config.Rollup{Enabled: true})
+	rollups.window = 50 * time.Millisecond
+
+	c.notify(event.StatemonitorEvent{Kind: "Pod", Name: "api-7d9-a", Namespace: "default", Reason: "Created"},
+		Event{eventType: "create", resourceType: "Pod", obj: &api_v1.Pod{ObjectMeta: owned("Pod", "api-7d9-a", "Deployment", "api")}}, "")
+	assert.Empty(t, slack.received())
+
+	// the pending groups are sent on termination, once
+	rollups.flushAll()
+	assert.Len(t, slack.received(), 1)
+	assert.Equal(t, "ChildrenChanged", slack.received()[0].Reason)
+
+	// the events following the termination aren't grouped
+	c.notify(event.StatemonitorEvent{Kind: "Pod", Name: "api-7d9-b", Namespace: "default", Reason: "Created"},
+		Event{eventType: "create", resourceType: "Pod", obj: &api_v1.Pod{ObjectMeta: owned("Pod", "api-7d9-b", "Deployment", "api")}}, "")
+	assert.Len(t, slack.received(), 2)
+	time.Sleep(100 * time.Millisecond)
+	assert.Len(t, slack.received(), 2)
+	assert.Empty(t, rollups.groups)
+}
+
+func TestDropWatchedChildren(t *testing.T) {
+	defer resetOwners()
+
+	registerOwnerStore("ReplicaSet", cache.NewStore(cache.MetaNamespaceKeyFunc))
+	dropWatchedChildren = true
+
+	slack := &recordingHandler{}
+	c := &Controller{eventHandlers: map[string]handlers.Handler{"slack": slack}}
+
+	c.notify(event.StatemonitorEvent{Kind: "Pod", Name: "api-7d9-a"}, Event{eventType: "create", resourceType: "Pod", obj: &api_v1.Pod{ObjectMeta: owned("Pod", "api-7d9-a", "ReplicaSet", "api-7d9")}}, "")
+	c.notify(event.StatemonitorEvent{Kind: "Pod", Name: "job-a"}, Event{eventType: "create", resourceType: "Pod", obj: &api_v1.Pod{ObjectMeta: owned("Pod", "job-a", "Job", "job")}}, "")
+
+	assert.Len(t, slack.events, 1)
+	assert.Equal(t, "job-a", slack.events[0].Name)
+}
@@ -0,0 +1,157 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/marvasgit/kubestatewatch/pkg/filter"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers"
+	"github.com/sirupsen/logrus"
+	"k8s.io/utils/strings/slices"
+)
+
+// route is a configured route with its compiled filter
+type route struct {
+	config.Route
+	filter     *filter.Expression
+	severities []string
+}
+
+// routes from the configuration, in order
+var routes []*route
+
+// getRoutes compiles the filters of the configured routes
+func getRoutes(conf []config.Route) ([]*route, error) {
+	var r []*route
+	for _, rc := range conf {
+		rt := &route{Route: rc}
+		for _, severity := range rc.Severities {
+			status, ok := event.StatusFromSeverity(severity)
+			if !ok {
+				return nil, fmt.Errorf("route %s: invalid severity %q, expected info, warning or critical", rc.Name, severity)
+			}
+			rt.severities = append(rt.severities, event.SeverityFromStatus(status))
+		}
+		if rc.Filter != "" {
+			var err error
+			if rt.filter, err = filter.CompileExpression(rc.Filter); err != nil {
+				return nil, fmt.Errorf("route %s: %v", rc.Name, err)
+			}
+		}
+		r = append(r, rt)
+	}
+	return r, nil
+}
+
+func findRoute(name string) *route {
+	for _, r := range routes {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// conditional reports whether the route selects the events by filter or severity
+func (r *route) conditional() bool {
+	return r.filter != nil || len(r.severities) > 0
+}
+
+// matchSeverity reports whether the severity of the event is one of the route's
+func (r *route) matchSeverity(in filter.Input) bool {
+	if len(r.severities) == 0 {
+		return true
+	}
+	severity, _ := in.Event["severity"].(string)
+	return slices.Contains(r.severities, severity)
+}
+
+// routesHaveConditions reports whether any route needs the filter input to be evaluated
+func routesHaveConditions() bool {
+	for _, r := range routes {
+		if r.conditional() {
+			return true
+		}
+	}
+	return false
+}
+
+// routeHandlers returns the handlers of the route, all the handlers for no route or a route without handlers
+func (c *Controller) routeHandlers(r *route) []handlers.Handler {
+	var eventHandlers []handlers.Handler
+	if r == nil || len(r.Handlers) == 0 {
+		for _, handler := range c.eventHandlers {
+			eventHandlers = append(eventHandlers, handler)
+		}
+		return eventHandlers
+	}
+
+	for _, name := range r.Handlers {
+		handler, ok := c.eventHandlers[name]
+		if !ok {
+			logrus.Warnf("Handler %s of route %s is not enabled", name, r.Name)
+			continue
+		}
+		eventHandlers = append(eventHandlers, handler)
+	}
+	return eventHandlers
+}
+
+// routedHandler is a handler an event is sent to with the name of the route selecting it, empty for all the handlers
+type routedHandler struct {
+	handler handlers.Handler
+	route   string
+}
+
+func routed(eventHandlers []handlers.Handler, routeName string) []routedHandler {
+	var rh []routedHandler
+	for _, handler := range eventHandlers {
+		rh = append(rh, routedHandler{handler, routeName})
+	}
+	return rh
+}
+
+// selectHandlers returns the handlers the event is sent to.
+// With a route annotation, the event goes to that route if it matches its severities and filter.
+// Otherwise it goes to every route with severities or a filter matching it, or to all the handlers when none does.
+func (c *Controller) selectHandlers(routeName string, in filter.Input) []routedHandler {
+	if routeName != "" {
+		r := findRoute(routeName)
+		if r == nil {
+			logrus.Warnf("Unknown route %s, sending to all handlers", routeName)
+			return routed(c.routeHandlers(nil), "")
+		}
+		if !r.matchSeverity(in) || !matchFilter(r.filter, in, "route/"+r.Name) {
+			return nil
+		}
+		return routed(c.routeHandlers(r), r.Name)
+	}
+
+	var eventHandlers []routedHandler
+	selected := map[handlers.Handler]bool{}
+	for _, r := range routes {
+		if !r.conditional() || !r.matchSeverity(in) || !matchFilter(r.filter, in, "route/"+r.Name) {
+			continue
+		}
+		// a handler of several matching routes receives the event once, by the first route
+		for _, handler := range c.routeHandlers(r) {
+			if !selected[handler] {
+				selected[handler] = true
+				eventHandlers = append(eventHandlers, routedHandler{handler, r.Name})
+			}
+		}
+	}
+	if len(selected) == 0 {
+		return routed(c.routeHandlers(nil), "")
+	}
+	return eventHandlers
+}
+
+// dispatch sends the event to the handlers selected by its route
+func (c *Controller) dispatch(e event.StatemonitorEvent, in filter.Input, routeName string) {
+	for _, rh := range c.selectHandlers(routeName, in) {
+		e.Route = rh.route
+		rh.handler.Handle(e)
+	}
+}
@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/marvasgit/kubestatewatch/pkg/filter"
+	"github.com/wI2L/jsondiff"
+	api_v1 "k8s.io/api/core/v1"
+	events_v1 "k8s.io/api/events/v1"
+)
+
+// severityRules from the configuration, in order
+var severityRules filter.SeverityRules
+
+// defaultSeverities are the severities of the events matching no severity rule, by event type
+var defaultSeverities = map[string]string{
+	"create":    event.SeverityInfo,
+	"update":    event.SeverityWarning,
+	"delete":    event.SeverityCritical,
+	"transient": event.SeverityWarning,
+}
+
+// configEventTypes maps the event types to the names used in the configuration
+var configEventTypes = map[string]string{
+	"create":    "add",
+	"update":    "update",
+	"delete":    "delete",
+	"transient": "transient",
+}
+
+// classify returns the status of an event from the severity rules
+func classify(e Event, patch jsondiff.Patch) string {
+	severity, ok := severityRules.Classify(filter.Subject{
+		Kind:      e.resourceType,
+		EventType: configEventTypes[e.eventType],
+		Namespace: e.namespace,
+		Reason:    eventReason(e.obj),
+		Patch:     patch,
+	})
+	if !ok {
+		severity = defaultSeverities[e.eventType]
+	}
+	status, _ := event.StatusFromSeverity(severity)
+	return status
+}
+
+// eventReason returns the reason of a core event, empty for other objects
+func eventReason(obj interface{}) string {
+	switch e := obj.(type) {
+	case *api_v1.Event:
+		return e.Reason
+	case *events_v1.Event:
+		return e.Reason
+	}
+	return ""
+}
+
+// classifyReason returns the status of an event which isn't a change of the object, e.g. a pod failure,
+// from the severity rules matching its reason
+func classifyReason(kind string, namespace string, reason string, defaultSeverity string) string {
+	severity, ok := severityRules.Classify(filter.Subject{Kind: kind, Namespace: namespace, Reason: reason})
+	if !ok {
+		severity = defaultSeverity
+	}
+	status, _ := event.StatusFromSeverity(severity)
+	return status
+}
+
+// notifyReason sends an event of the object which isn't one of its changes, e.g. a pod failure or a node condition,
+// its severity set by the severity rules matching its reason or the annotations of the object
+func (c *Controller) notifyReason(newEvent Event, o overrides, reason string, details string, defaultSeverity string) {
+	status := classifyReason(newEvent.resourceType, newEvent.namespace, reason, defaultSeverity)
+	if o.status != "" {
+		status = o.status
+	}
+	e := event.StatemonitorEvent{
+		Name:       newEvent.key,
+		Namespace:  newEvent.namespace,
+		Kind:       newEvent.resourceType,
+		ApiVersion: newEvent.apiVersion,
+		Status:     status,
+		Reason:     reason,
+		Details:    details,
+	}
+	c.notifyFollowUp(e, newEvent, o.route)
+}
@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/marvasgit/kubestatewatch/pkg/filter"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers"
+	"github.com/stretchr/testify/assert"
+	"github.com/wI2L/jsondiff"
+	api_v1 "k8s.io/api/core/v1"
+)
+
+func TestClassify(t *testing.T) {
+	var err error
+	severityRules, err = filter.CompileSeverityRules([]config.SeverityRule{
+		{Kinds: []string{"Event"}, Reasons: []string{"BackOff"}, Severity: "critical"},
+		{Kinds: []string{"Event"}, Severity: "info"},
+		{EventTypes: []string{"add"}, Namespaces: []string{"prod"}, Severity: "warning"},
+		{Paths: []string{"/spec/replicas"}, Severity: "info"},
+	})
+	assert.NoError(t, err)
+	defer func() { severityRules = nil }()
+
+	backoff := &api_v1.Event{Reason: "BackOff"}
+	scale := jsondiff.Patch{{Type: jsondiff.OperationReplace, Path: "/spec/replicas"}}
+
+	assert.Equal(t, "Danger", classify(Event{eventType: "create", resourceType: "Event", obj: backoff}, nil))
+	assert.Equal(t, "Normal", classify(Event{eventType: "create", resourceType: "Event", obj: &api_v1.Event{Reason: "Pulled"}}, nil))
+	assert.Equal(t, "Warning", classify(Event{eventType: "create", resourceType: "Pod", namespace: "prod"}, nil))
+	assert.Equal(t, "Normal", classify(Event{eventType: "update", resourceType: "Deployment"}, scale))
+	// defaults by event type
+	assert.Equal(t, "Normal", classify(Event{eventType: "create", resourceType: "Pod"}, nil))
+	assert.Equal(t, "Warning", classify(Event{eventType: "update", resourceType: "Deployment"}, nil))
+	assert.Equal(t, "Danger", classify(Event{eventType: "delete", resourceType: "Deployment"}, nil))
+}
+
+func TestSeverityRoutes(t *testing.T) {
+	msteams, pagers := &recordingHandler{}, &recordingHandler{}
+	c := &Controller{eventHandlers: map[string]handlers.Handler{"msteams": msteams, "smtp": pagers}}
+	var err error
+	routes, err = getRoutes([]config.Route{
+		{Name: "oncall", Handlers: []string{"smtp"}, Severities: []string{"critical"}},
+	})
+	assert.NoError(t, err)
+	defer func() { routes = nil }()
+
+	critical := event.StatemonitorEvent{Name: "foo", Status: "Danger"}
+	warning := event.StatemonitorEvent{Name: "foo", Status: "Warning"}
+	// critical events only go to the route, the others to all the handlers
+	c.notify(critical, Event{eventType: "delete"}, "")
+	c.notify(warning, Event{eventType: "update"}, "")
+	// the annotated route only receives the critical events
+	c.notify(warning, Event{eventType: "update"}, "oncall")
+
+	assert.Len(t, pagers.events, 2)
+	assert.Len(t, msteams.events, 1)
+
+	_, err = getRoutes([]config.Route{{Name: "broken", Severities: []string{"loud"}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"reflect"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+)
+
+const lastAppliedConfigAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// newTransform returns the informer transform normalizing objects before they are cached.
+// It drops the fields that are only noise in the diffs and take most of the memory:
+// managedFields, resourceVersion, the last-applied-configuration annotation and optionally the status.
+func newTransform(resourceConfig config.ResourceConfig) cache.TransformFunc {
+	return func(obj interface{}) (interface{}, error) {
+		// tombstones are passed through, their object was already transformed when cached
+		if _, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			return obj, nil
+		}
+
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return obj, nil
+		}
+
+		if !resourceConfig.KeepManagedFields {
+			accessor.SetManagedFields(nil)
+		}
+		accessor.SetResourceVersion("")
+
+		if annotations := accessor.GetAnnotations(); annotations != nil {
+			if _, ok := annotations[lastAppliedConfigAnnotation]; ok {
+				delete(annotations, lastAppliedConfigAnnotation)
+				accessor.SetAnnotations(annotations)
+			}
+		}
+
+		if resourceConfig.DropStatus {
+			dropStatus(obj)
+		}
+		return obj, nil
+	}
+}
+
+// dropStatus resets the Status field of typed objects and removes the status of unstructured objects
+func dropStatus(obj interface{}) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		unstructured.RemoveNestedField(u.Object, "status")
+		return
+	}
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return
+	}
+	status := v.Elem().FieldByName("Status")
+	if status.IsValid() && status.CanSet() {
+		status.Set(reflect.Zero(status.Type()))
+	}
+}
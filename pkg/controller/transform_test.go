@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newTestPod(i int) *api_v1.Pod {
+	return &api_v1.Pod{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:            fmt.Sprintf("pod-%d", i),
+			Namespace:       "default",
+			ResourceVersion: "42",
+			Annotations: map[string]string{
+				lastAppliedConfigAnnotation: strings.Repeat(`{"apiVersion":"v1","kind":"Pod"}`, 32),
+				"team":                      "core",
+			},
+			ManagedFields: []meta_v1.ManagedFieldsEntry{
+				{Manager: "kubectl", Operation: meta_v1.ManagedFieldsOperationApply, FieldsType: "FieldsV1",
+					FieldsV1: &meta_v1.FieldsV1{Raw: []byte(strings.Repeat(`{"f:metadata":{}}`, 32))}},
+				{Manager: "kubelet", Operation: meta_v1.ManagedFieldsOperationUpdate, Subresource: "status", FieldsType: "FieldsV1",
+					FieldsV1: &meta_v1.FieldsV1{Raw: []byte(strings.Repeat(`{"f:status":{}}`, 32))}},
+			},
+		},
+		Spec: api_v1.PodSpec{Containers: []api_v1.Container{{Name: "app", Image: "nginx"}}},
+		Status: api_v1.PodStatus{
+			Phase:             api_v1.PodRunning,
+			ContainerStatuses: []api_v1.ContainerStatus{{Name: "app", Ready: true, RestartCount: 1}},
+		},
+	}
+}
+
+func TestTransform(t *testing.T) {
+	transform := newTransform(config.ResourceConfig{})
+	obj, err := transform(newTestPod(1))
+	assert.NoError(t, err)
+
+	pod := obj.(*api_v1.Pod)
+	assert.Nil(t, pod.ManagedFields)
+	assert.Empty(t, pod.ResourceVersion)
+	assert.Equal(t, map[string]string{"team": "core"}, pod.Annotations)
+	assert.Equal(t, api_v1.PodRunning, pod.Status.Phase)
+
+	transform = newTransform(config.ResourceConfig{KeepManagedFields: true, DropStatus: true})
+	obj, err = transform(newTestPod(1))
+	assert.NoError(t, err)
+
+	pod = obj.(*api_v1.Pod)
+	assert.Len(t, pod.ManagedFields, 2)
+	assert.Equal(t, api_v1.PodStatus{}, pod.Status)
+
+	tombstone := cache.DeletedFinalStateUnknown{Key: "default/pod-1", Obj: newTestPod(1)}
+	obj, err = transform(tombstone)
+	assert.NoError(t, err)
+	assert.Equal(t, tombstone, obj)
+}
+
+func TestTransformUnstructured(t *testing.T) {
+	crd := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apiextensions.k8s.io/v1",
+		"kind":       "CustomResourceDefinition",
+		"metadata":   map[string]interface{}{"name": "widgets.example.com", "resourceVersion": "42"},
+		"spec":       map[string]interface{}{"group": "example.com"},
+		"status":     map[string]interface{}{"acceptedNames": map[string]interface{}{"kind": "Widget"}},
+	}}
+
+	obj, err := newTransform(config.ResourceConfig{DropStatus: true})(crd)
+	assert.NoError(t, err)
+
+	u := obj.(*unstructured.Unstructured)
+	assert.Empty(t, u.GetResourceVersion())
+	assert.NotContains(t, u.Object, "status")
+	assert.Equal(t, "widgets.example.com", utils.GetObjectMetaData(u).Name)
+}
+
+// BenchmarkInformerTransform compares the heap used by a pod informer cache with and without the transform
+func BenchmarkInformerTransform(b *testing.B) {
+	const pods = 2000
+	var objects []k8sruntime.Object
+	for i := 0; i < pods; i++ {
+		objects = append(objects, newTestPod(i))
+	}
+
+	for _, bc := range []struct {
+		name      string
+		transform cache.TransformFunc
+	}{
+		{"raw", nil},
+		{"transformed", newTransform(config.ResourceConfig{})},
+		{"transformed-dropstatus", newTransform(config.ResourceConfig{DropStatus: true})},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				client := fake.NewSimpleClientset(objects...)
+				b.ReportMetric(float64(informerHeap(b, client, bc.transform))/pods, "B/cached-pod")
+			}
+		})
+	}
+}
+
+func informerHeap(b *testing.B, client kubernetes.Interface, transform cache.TransformFunc) uint64 {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	informer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options meta_v1.ListOptions) (k8sruntime.Object, error) {
+				return client.CoreV1().Pods("").List(context.Background(), options)
+			},
+			WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+				return client.CoreV1().Pods("").Watch(context.Background(), options)
+			},
+		},
+		&api_v1.Pod{},
+		0, //Skip resync
+		cache.Indexers{},
+	)
+	if transform != nil {
+		if err := informer.SetTransform(transform); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	stopCh := make(chan struct{})
+	go informer.Run(stopCh)
+	if !cache.WaitForCacheSync(stopCh, informer.HasSynced) {
+		b.Fatal("timed out waiting for caches to sync")
+	}
+
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+
+	// wait for the informer to stop so its cache is released before the next run
+	close(stopCh)
+	for !informer.IsStopped() {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if after.HeapAlloc < before.HeapAlloc {
+		return 0
+	}
+	return after.HeapAlloc - before.HeapAlloc
+}
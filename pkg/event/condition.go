@@ -0,0 +1,48 @@
+package event
+
+import "strings"
+
+// conditions are the reasons of the events reporting a problem which is followed by a recovery event
+var conditions = map[string]bool{
+	"NodeNotReady":           true,
+	"NodeMemoryPressure":     true,
+	"NodeDiskPressure":       true,
+	"NodePIDPressure":        true,
+	"NodeNetworkUnavailable": true,
+	"RolloutFailed":          true,
+}
+
+// recoveries maps the reasons of the recovery events to the condition they end,
+// the conditions are also ended by the events of their reason followed by "Resolved"
+var recoveries = map[string]string{
+	"NodeReady":       "NodeNotReady",
+	"RolloutComplete": "RolloutFailed",
+}
+
+const resolvedSuffix = "Resolved"
+
+// Condition returns the condition the event is about for the problems ending with a recovery event,
+// e.g. NodeNotReady for both the NodeNotReady and the NodeReady events, and whether the event is the recovery.
+// The condition is empty for the other events
+func (e *StatemonitorEvent) Condition() (condition string, recovered bool) {
+	if conditions[e.Reason] {
+		return e.Reason, false
+	}
+	if condition, ok := recoveries[e.Reason]; ok {
+		return condition, true
+	}
+	if condition := strings.TrimSuffix(e.Reason, resolvedSuffix); condition != e.Reason && conditions[condition] {
+		return condition, true
+	}
+	return "", false
+}
+
+// IncidentKey identifies the incidents of the event's object in the cluster, e.g. "prod/Deployment/default/api".
+// The conditions of the object followed by a recovery have their own incident, e.g. "prod/Node//node-1/NodeNotReady"
+func (e *StatemonitorEvent) IncidentKey(cluster string) string {
+	key := strings.Join([]string{cluster, e.Kind, e.Namespace, e.Name}, "/")
+	if condition, _ := e.Condition(); condition != "" {
+		key += "/" + condition
+	}
+	return key
+}
@@ -31,13 +31,24 @@ type StatemonitorEvent struct {
 	Status     string
 	Name       string
 	Diff       string
+	// Children lists the events of the owned objects rolled up into the event of their owner
+	Children []string
+	// Details describes events that aren't changes of the object, e.g. "rollout complete in 2m13s"
+	Details string
+	// Labels of the object
+	Labels map[string]string
+	// Route is the name of the route selecting the handler the event is sent to, empty when sent to all the handlers
+	Route string
+	// Actor is the field manager of the last change of the object, e.g. kubectl or helm, when its managed fields are kept
+	Actor string
 }
 
 // Message returns event message in standard format.
 // included as a part of event packege to enhance code resuablity across handlers.
 func (e *StatemonitorEvent) Message() (msg string) {
 	// using switch over if..else, since the format could vary based on the kind of the object in future.
-	switch e.Kind {
+	kind := messageKind(e)
+	switch kind {
 	case "namespace":
 		msg = fmt.Sprintf(
 			"A namespace `%s` has been `%s`",
@@ -81,9 +92,24 @@ func (e *StatemonitorEvent) Message() (msg string) {
 	default:
 		msg = createBoxlikeOutput(e)
 	}
+	// the node health messages are followed by the details of the change
+	if kind != e.Kind && e.Details != "" {
+		msg += "\n" + e.Details
+	}
 	return msg
 }
 
+// messageKind returns the kind of message of the event, the node health events having their own messages
+func messageKind(e *StatemonitorEvent) string {
+	if e.Kind == "Node" {
+		switch e.Reason {
+		case "NodeReady", "NodeNotReady", "NodeRebooted":
+			return e.Reason
+		}
+	}
+	return e.Kind
+}
+
 func createBoxlikeOutput(e *StatemonitorEvent) string {
 	var sb strings.Builder
 	sb.Grow(1200)
@@ -94,6 +120,9 @@ func createBoxlikeOutput(e *StatemonitorEvent) string {
 	if len(e.Name) > col2Width {
 		col2Width = len(e.Name) + 2
 	}
+	if e.Details != "" {
+		sb.WriteString(e.Details + "\n")
+	}
 	sb.WriteString(e.Diff + "\n")
 
 	dataRow(&sb, col1Width, col2Width, "Type", e.Kind)
@@ -101,6 +130,9 @@ func createBoxlikeOutput(e *StatemonitorEvent) string {
 	dataRow(&sb, col1Width, col2Width, "Action", e.Reason)
 	dataRow(&sb, col1Width, col2Width, "Namespace", e.Namespace)
 	dataRow(&sb, col1Width, col2Width, "Status", e.Status)
+	for _, child := range e.Children {
+		dataRow(&sb, col1Width, col2Width, "Child", child)
+	}
 	sb.WriteString(fmt.Sprintf("+%s+%s+\n", strings.Repeat("-", col1Width), strings.Repeat("-", col2Width)))
 
 	return sb.String()
@@ -0,0 +1,54 @@
+package event
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Record is the JSON document of an event published to the streams, indexes and archives of events
+type Record struct {
+	Time       time.Time         `json:"time"`
+	Cluster    string            `json:"cluster,omitempty"`
+	Kind       string            `json:"kind"`
+	ApiVersion string            `json:"apiVersion,omitempty"`
+	Namespace  string            `json:"namespace,omitempty"`
+	Name       string            `json:"name"`
+	Reason     string            `json:"reason"`
+	Status     string            `json:"status,omitempty"`
+	Severity   string            `json:"severity"`
+	Details    string            `json:"details,omitempty"`
+	Children   []string          `json:"children,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Route      string            `json:"route,omitempty"`
+	Actor      string            `json:"actor,omitempty"`
+	// Diff is the JSON patch of an update, kept as a string when it isn't valid JSON
+	Diff json.RawMessage `json:"diff,omitempty"`
+}
+
+// NewRecord returns the record of the event in the cluster at the given time
+func NewRecord(e StatemonitorEvent, cluster string, t time.Time) Record {
+	r := Record{
+		Time:       t,
+		Cluster:    cluster,
+		Kind:       e.Kind,
+		ApiVersion: e.ApiVersion,
+		Namespace:  e.Namespace,
+		Name:       e.Name,
+		Reason:     e.Reason,
+		Status:     e.Status,
+		Severity:   SeverityFromStatus(e.Status),
+		Details:    e.Details,
+		Children:   e.Children,
+		Labels:     e.Labels,
+		Route:      e.Route,
+		Actor:      e.Actor,
+	}
+	if e.Diff != "" {
+		if json.Valid([]byte(e.Diff)) {
+			r.Diff = json.RawMessage(e.Diff)
+		} else {
+			r.Diff, _ = json.Marshal(e.Diff)
+		}
+	}
+	return r
+}
@@ -0,0 +1,34 @@
+package event
+
+import "strings"
+
+// Severity levels of the notifications, a notification's Status is derived from its severity
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// StatusFromSeverity maps a severity to the status of the notifications used by the handlers for their colors
+func StatusFromSeverity(severity string) (string, bool) {
+	switch strings.ToLower(severity) {
+	case SeverityInfo, "normal":
+		return "Normal", true
+	case SeverityWarning:
+		return "Warning", true
+	case SeverityCritical, "danger":
+		return "Danger", true
+	}
+	return "", false
+}
+
+// SeverityFromStatus maps the status of a notification back to its severity
+func SeverityFromStatus(status string) string {
+	switch status {
+	case "Warning":
+		return SeverityWarning
+	case "Danger":
+		return SeverityCritical
+	}
+	return SeverityInfo
+}
@@ -0,0 +1,106 @@
+package filter
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+)
+
+// Expression is a compiled CEL filter expression.
+// It is evaluated against the variables:
+//   - event: the notification (kind, name, namespace, apiVersion, reason, status, severity)
+//   - object: the watched object, empty on deletes of unknown objects
+//   - oldObject: the object before the update, empty for creates and deletes
+//   - diff: the changes of an update as JSON patch operations (op, path, value)
+//   - namespaceLabels: the labels of the namespace of the object
+//
+// For example: `diff.exists(d, d.path.endsWith("/image") || d.path == "/spec/replicas") && namespaceLabels[?"env"] == optional.of("prod")`
+type Expression struct {
+	raw     string
+	program cel.Program
+}
+
+// Input holds the values of the variables an Expression is evaluated against
+type Input struct {
+	Event           map[string]interface{}
+	Object          map[string]interface{}
+	OldObject       map[string]interface{}
+	Diff            []interface{}
+	NamespaceLabels map[string]string
+}
+
+var celEnv *cel.Env
+
+func init() {
+	var err error
+	celEnv, err = cel.NewEnv(
+		cel.Variable("event", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("object", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("oldObject", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("diff", cel.ListType(cel.MapType(cel.StringType, cel.DynType))),
+		cel.Variable("namespaceLabels", cel.MapType(cel.StringType, cel.StringType)),
+		cel.OptionalTypes(),
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// CompileExpression parses and type-checks a CEL filter expression, it must evaluate to a bool
+func CompileExpression(expr string) (*Expression, error) {
+	ast, iss := celEnv.Compile(expr)
+	if iss.Err() != nil {
+		return nil, fmt.Errorf("invalid filter %q: %v", expr, iss.Err())
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("invalid filter %q: must evaluate to a bool, got %v", expr, ast.OutputType())
+	}
+	program, err := celEnv.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %v", expr, err)
+	}
+	return &Expression{raw: expr, program: program}, nil
+}
+
+// String returns the expression as it was written in the configuration
+func (e *Expression) String() string {
+	return e.raw
+}
+
+// Match evaluates the expression, a nil expression matches every input
+func (e *Expression) Match(in Input) (bool, error) {
+	if e == nil {
+		return true, nil
+	}
+
+	vars := map[string]interface{}{
+		"event":           emptyIfNil(in.Event),
+		"object":          emptyIfNil(in.Object),
+		"oldObject":       emptyIfNil(in.OldObject),
+		"diff":            in.Diff,
+		"namespaceLabels": in.NamespaceLabels,
+	}
+	if in.Diff == nil {
+		vars["diff"] = []interface{}{}
+	}
+	if in.NamespaceLabels == nil {
+		vars["namespaceLabels"] = map[string]string{}
+	}
+
+	out, _, err := e.program.Eval(vars)
+	if err != nil {
+		return false, fmt.Errorf("error evaluating filter %q: %v", e.raw, err)
+	}
+	match, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("error evaluating filter %q: not a bool", e.raw)
+	}
+	return match, nil
+}
+
+func emptyIfNil(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return map[string]interface{}{}
+	}
+	return m
+}
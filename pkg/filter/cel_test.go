@@ -0,0 +1,82 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileExpression(t *testing.T) {
+	var Tests = []struct {
+		expr string
+		err  bool
+	}{
+		{`event.reason == "Updated"`, false},
+		{`diff.exists(d, d.path == "/spec/replicas")`, false},
+		{`namespaceLabels[?"env"] == optional.of("prod")`, false},
+		{`event.reason`, true},
+		{`namespaceLabels.env + 1 == 2`, true},
+		{`unknown == 1`, true},
+		{`event.reason ==`, true},
+	}
+
+	for _, tt := range Tests {
+		_, err := CompileExpression(tt.expr)
+		if tt.err {
+			assert.Error(t, err, tt.expr)
+		} else {
+			assert.NoError(t, err, tt.expr)
+		}
+	}
+}
+
+func TestExpressionMatch(t *testing.T) {
+	deploymentUpdate := Input{
+		Event: map[string]interface{}{"kind": "Deployment", "reason": "Updated", "namespace": "shop"},
+		Object: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "api", "labels": map[string]interface{}{"tier": "critical"}},
+			"spec":     map[string]interface{}{"replicas": int64(3)},
+		},
+		OldObject: map[string]interface{}{
+			"spec": map[string]interface{}{"replicas": int64(2)},
+		},
+		Diff: []interface{}{
+			map[string]interface{}{"op": "replace", "path": "/spec/replicas", "value": int64(3)},
+		},
+		NamespaceLabels: map[string]string{"env": "prod"},
+	}
+
+	var Tests = []struct {
+		expr  string
+		input Input
+		match bool
+		err   bool
+	}{
+		{`event.reason == "Updated"`, deploymentUpdate, true, false},
+		{`diff.exists(d, d.path.endsWith("/image") || d.path == "/spec/replicas") && namespaceLabels[?"env"] == optional.of("prod")`, deploymentUpdate, true, false},
+		{`diff.exists(d, d.path.endsWith("/image"))`, deploymentUpdate, false, false},
+		{`object.spec.replicas > oldObject.spec.replicas`, deploymentUpdate, true, false},
+		{`object.metadata.labels.tier == "critical"`, deploymentUpdate, true, false},
+		{`namespaceLabels[?"env"] == optional.of("prod")`, Input{}, false, false},
+		{`size(diff) == 0`, Input{}, true, false},
+		{`object.spec.replicas > 1`, Input{}, false, true},
+	}
+
+	for _, tt := range Tests {
+		e, err := CompileExpression(tt.expr)
+		assert.NoError(t, err, tt.expr)
+
+		match, err := e.Match(tt.input)
+		if tt.err {
+			assert.Error(t, err, tt.expr)
+			continue
+		}
+		assert.NoError(t, err, tt.expr)
+		assert.Equal(t, tt.match, match, tt.expr)
+	}
+
+	var nilExpression *Expression
+	match, err := nilExpression.Match(deploymentUpdate)
+	assert.NoError(t, err)
+	assert.True(t, match)
+}
@@ -0,0 +1,135 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/wI2L/jsondiff"
+	"k8s.io/utils/strings/slices"
+)
+
+type ignoreRule struct {
+	path        *Pattern
+	kinds       []string
+	namespaces  []string
+	value       *regexp.Regexp
+	onlyChanged bool
+}
+
+// IgnoreRules is a compiled set of config.IgnoreRule
+type IgnoreRules []*ignoreRule
+
+// CompileIgnoreRules validates and compiles the ignore rules from the configuration
+func CompileIgnoreRules(rules []config.IgnoreRule) (IgnoreRules, error) {
+	var compiled IgnoreRules
+	for _, r := range rules {
+		path, err := CompilePattern(r.Path)
+		if err != nil {
+			return nil, err
+		}
+		rule := &ignoreRule{
+			path:        path,
+			namespaces:  r.Namespaces,
+			onlyChanged: r.OnlyChanged,
+		}
+		for _, kind := range r.Kinds {
+			rule.kinds = append(rule.kinds, strings.ToLower(kind))
+		}
+		if r.Value != "" {
+			if rule.value, err = regexp.Compile(r.Value); err != nil {
+				return nil, fmt.Errorf("invalid value expression %q for ignore rule %q: %v", r.Value, r.Path, err)
+			}
+		}
+		compiled = append(compiled, rule)
+	}
+	return compiled, nil
+}
+
+// appliesTo checks the kind and namespace scope of the rule
+func (r *ignoreRule) appliesTo(kind string, namespace string) bool {
+	if len(r.kinds) > 0 && !slices.Contains(r.kinds, strings.ToLower(kind)) {
+		return false
+	}
+	if len(r.namespaces) > 0 && !slices.Contains(r.namespaces, namespace) {
+		return false
+	}
+	return true
+}
+
+func (r *ignoreRule) match(op jsondiff.Operation) bool {
+	if !r.path.Match(op.Path) {
+		return false
+	}
+	if r.value == nil {
+		return true
+	}
+	value := op.Value
+	if value == nil {
+		value = op.OldValue
+	}
+	return r.value.MatchString(valueString(value))
+}
+
+func valueString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// Apply removes the ignored operations from the patch for an object of the given kind and namespace
+func (rules IgnoreRules) Apply(kind string, namespace string, patch jsondiff.Patch) jsondiff.Patch {
+	var always, onlyChanged IgnoreRules
+	for _, r := range rules {
+		if !r.appliesTo(kind, namespace) {
+			continue
+		}
+		if r.onlyChanged {
+			onlyChanged = append(onlyChanged, r)
+		} else {
+			always = append(always, r)
+		}
+	}
+	if len(always) == 0 && len(onlyChanged) == 0 {
+		return patch
+	}
+
+	var result jsondiff.Patch
+	for _, op := range patch {
+		if !always.matchAny(op) {
+			result = append(result, op)
+		}
+	}
+
+	for _, r := range onlyChanged {
+		if len(result) > 0 && r.matchAll(result) {
+			return nil
+		}
+	}
+	return result
+}
+
+func (rules IgnoreRules) matchAny(op jsondiff.Operation) bool {
+	for _, r := range rules {
+		if r.match(op) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *ignoreRule) matchAll(patch jsondiff.Patch) bool {
+	for _, op := range patch {
+		if !r.match(op) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,126 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/wI2L/jsondiff"
+)
+
+func TestPatternMatch(t *testing.T) {
+	var Tests = []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{"/status", "/status", true},
+		{"/status", "/status/conditions/0/status", true},
+		{"/status", "/spec", false},
+		{"/spec/template/spec/containers/*/env/*/value", "/spec/template/spec/containers/0/env/3/value", true},
+		{"/spec/template/spec/containers/*/env/*/value", "/spec/template/spec/containers/0/env/3/name", false},
+		{"/spec/*/image", "/spec/template/spec/containers/0/image", false},
+		{"/**/image", "/spec/template/spec/containers/0/image", true},
+		{"/spec/**/resources", "/spec/resources", true},
+		{"/metadata/annotations/kubectl.kubernetes.io~1*", "/metadata/annotations/kubectl.kubernetes.io~1last-applied-configuration", true},
+		{"/metadata/annotations/kubectl.kubernetes.io~1*", "/metadata/annotations/deployment.kubernetes.io~1revision", false},
+		{"/metadata/labels/re:app\\..*", "/metadata/labels/app.version", true},
+		{"/metadata/labels/re:app\\..*", "/metadata/labels/tier", false},
+		{"/data/*.json", "/data/appsettings.json", true},
+	}
+
+	for _, tt := range Tests {
+		p, err := CompilePattern(tt.pattern)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.match, p.Match(tt.path), "pattern %s on %s", tt.pattern, tt.path)
+	}
+}
+
+func TestCompilePatternErrors(t *testing.T) {
+	_, err := CompilePattern("status")
+	assert.Error(t, err)
+
+	// the empty patterns would match every path
+	_, err = CompilePattern("")
+	assert.Error(t, err)
+	_, err = CompilePattern("/")
+	assert.Error(t, err)
+	_, err = CompileIgnoreRules([]config.IgnoreRule{{Kinds: []string{"Secret"}}})
+	assert.Error(t, err)
+
+	_, err = CompilePattern("/metadata/labels/re:(")
+	assert.Error(t, err)
+
+	_, err = CompileIgnoreRules([]config.IgnoreRule{{Path: "/spec", Value: "("}})
+	assert.Error(t, err)
+}
+
+var testPatch = jsondiff.Patch{
+	{Type: jsondiff.OperationReplace, Path: "/spec/template/spec/containers/0/image", Value: "nginx:1.25"},
+	{Type: jsondiff.OperationReplace, Path: "/spec/template/spec/containers/0/env/0/value", Value: "debug"},
+	{Type: jsondiff.OperationAdd, Path: "/metadata/annotations/kubectl.kubernetes.io~1restartedAt", Value: "2023-11-01T10:00:00Z"},
+}
+
+func TestIgnoreRulesApply(t *testing.T) {
+	var Tests = []struct {
+		name      string
+		rules     []config.IgnoreRule
+		kind      string
+		namespace string
+		expected  []string
+	}{
+		{
+			name:     "no rules",
+			kind:     "Deployment",
+			expected: []string{"/spec/template/spec/containers/0/image", "/spec/template/spec/containers/0/env/0/value", "/metadata/annotations/kubectl.kubernetes.io~1restartedAt"},
+		},
+		{
+			name:     "wildcards",
+			rules:    []config.IgnoreRule{{Path: "/spec/template/spec/containers/*/env/*/value"}, {Path: "/metadata/annotations/kubectl.kubernetes.io~1*"}},
+			kind:     "Deployment",
+			expected: []string{"/spec/template/spec/containers/0/image"},
+		},
+		{
+			name:     "kind scope",
+			rules:    []config.IgnoreRule{{Path: "/**/image", Kinds: []string{"statefulset"}}},
+			kind:     "Deployment",
+			expected: []string{"/spec/template/spec/containers/0/image", "/spec/template/spec/containers/0/env/0/value", "/metadata/annotations/kubectl.kubernetes.io~1restartedAt"},
+		},
+		{
+			name:      "namespace scope",
+			rules:     []config.IgnoreRule{{Path: "/spec", Namespaces: []string{"dev"}}},
+			kind:      "Deployment",
+			namespace: "dev",
+			expected:  []string{"/metadata/annotations/kubectl.kubernetes.io~1restartedAt"},
+		},
+		{
+			name:     "value condition",
+			rules:    []config.IgnoreRule{{Path: "/**/image", Value: "^nginx:"}, {Path: "/**/env", Value: "^info$"}},
+			kind:     "Deployment",
+			expected: []string{"/spec/template/spec/containers/0/env/0/value", "/metadata/annotations/kubectl.kubernetes.io~1restartedAt"},
+		},
+		{
+			name:     "only changed with other changes",
+			rules:    []config.IgnoreRule{{Path: "/metadata", OnlyChanged: true}},
+			kind:     "Deployment",
+			expected: []string{"/spec/template/spec/containers/0/image", "/spec/template/spec/containers/0/env/0/value", "/metadata/annotations/kubectl.kubernetes.io~1restartedAt"},
+		},
+		{
+			name:     "only changed",
+			rules:    []config.IgnoreRule{{Path: "/spec"}, {Path: "/metadata", OnlyChanged: true}},
+			kind:     "Deployment",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range Tests {
+		rules, err := CompileIgnoreRules(tt.rules)
+		assert.NoError(t, err)
+
+		var paths []string
+		for _, op := range rules.Apply(tt.kind, tt.namespace, testPatch) {
+			paths = append(paths, op.Path)
+		}
+		assert.Equal(t, tt.expected, paths, tt.name)
+	}
+}
@@ -0,0 +1,155 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type segmentKind int
+
+const (
+	segmentLiteral segmentKind = iota
+	// segmentAny matches exactly one path segment ("*")
+	segmentAny
+	// segmentDeep matches zero or more path segments ("**")
+	segmentDeep
+	// segmentRegex matches one segment against a regular expression ("re:<expr>" or a glob)
+	segmentRegex
+)
+
+type segment struct {
+	kind    segmentKind
+	literal string
+	re      *regexp.Regexp
+}
+
+// Pattern is a compiled JSON pointer pattern.
+// Segments are separated by "/" and escaped the same way as JSON pointers ("~1" for "/", "~0" for "~").
+// A segment can be:
+//   - "*" to match any single segment (a map key or an array index)
+//   - "**" to match any number of segments, including none
+//   - "re:<expr>" to match the segment against a regular expression
+//   - a glob like "kubectl.kubernetes.io~1*" where "*" and "?" match within the segment
+//   - a literal key
+//
+// A pattern matches a path when it matches the path itself or one of its parents,
+// so "/status" matches "/status/conditions/0/lastTransitionTime".
+type Pattern struct {
+	raw      string
+	segments []segment
+}
+
+// CompilePattern parses a JSON pointer pattern
+func CompilePattern(pattern string) (*Pattern, error) {
+	if !strings.HasPrefix(pattern, "/") {
+		return nil, fmt.Errorf("invalid path pattern %q: must start with \"/\"", pattern)
+	}
+	// the pattern of the root would match every path
+	if pattern == "/" {
+		return nil, fmt.Errorf("invalid path pattern %q: matches every path", pattern)
+	}
+
+	p := &Pattern{raw: pattern}
+	for _, s := range splitPointer(pattern) {
+		seg, err := compileSegment(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path pattern %q: %v", pattern, err)
+		}
+		p.segments = append(p.segments, seg)
+	}
+	return p, nil
+}
+
+// MustCompilePattern is like CompilePattern but panics if the pattern cannot be parsed
+func MustCompilePattern(pattern string) *Pattern {
+	p, err := CompilePattern(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func compileSegment(s string) (segment, error) {
+	switch {
+	case s == "*":
+		return segment{kind: segmentAny}, nil
+	case s == "**":
+		return segment{kind: segmentDeep}, nil
+	case strings.HasPrefix(s, "re:"):
+		re, err := regexp.Compile("^(?:" + s[len("re:"):] + ")$")
+		if err != nil {
+			return segment{}, err
+		}
+		return segment{kind: segmentRegex, re: re}, nil
+	case strings.ContainsAny(s, "*?"):
+		var sb strings.Builder
+		sb.WriteString("^")
+		for _, r := range s {
+			switch r {
+			case '*':
+				sb.WriteString(".*")
+			case '?':
+				sb.WriteString(".")
+			default:
+				sb.WriteString(regexp.QuoteMeta(string(r)))
+			}
+		}
+		sb.WriteString("$")
+		return segment{kind: segmentRegex, re: regexp.MustCompile(sb.String())}, nil
+	default:
+		return segment{kind: segmentLiteral, literal: s}, nil
+	}
+}
+
+// String returns the pattern as it was written in the configuration
+func (p *Pattern) String() string {
+	return p.raw
+}
+
+// Match reports whether the JSON pointer path, or one of its parents, matches the pattern
+func (p *Pattern) Match(path string) bool {
+	return matchSegments(p.segments, splitPointer(path))
+}
+
+func matchSegments(pattern []segment, path []string) bool {
+	if len(pattern) == 0 {
+		return true
+	}
+	seg := pattern[0]
+	if seg.kind == segmentDeep {
+		for i := 0; i <= len(path); i++ {
+			if matchSegments(pattern[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(path) == 0 {
+		return false
+	}
+	switch seg.kind {
+	case segmentLiteral:
+		if seg.literal != path[0] {
+			return false
+		}
+	case segmentRegex:
+		if !seg.re.MatchString(path[0]) {
+			return false
+		}
+	}
+	return matchSegments(pattern[1:], path[1:])
+}
+
+// splitPointer splits a JSON pointer into its unescaped segments
+func splitPointer(ptr string) []string {
+	if ptr == "" || ptr == "/" {
+		return nil
+	}
+	parts := strings.Split(strings.TrimPrefix(ptr, "/"), "/")
+	for i, part := range parts {
+		part = strings.ReplaceAll(part, "~1", "/")
+		parts[i] = strings.ReplaceAll(part, "~0", "~")
+	}
+	return parts
+}
@@ -0,0 +1,104 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/wI2L/jsondiff"
+	"k8s.io/utils/strings/slices"
+)
+
+type severityRule struct {
+	kinds      []string
+	eventTypes []string
+	namespaces []string
+	paths      []*Pattern
+	reasons    []string
+	severity   string
+}
+
+// SeverityRules is a compiled table of config.SeverityRule
+type SeverityRules []*severityRule
+
+// Subject is what the severity rules classify
+type Subject struct {
+	Kind      string
+	EventType string
+	Namespace string
+	// Reason of a core event
+	Reason string
+	// Patch holds the changes of an update
+	Patch jsondiff.Patch
+}
+
+// CompileSeverityRules validates and compiles the severity rules from the configuration
+func CompileSeverityRules(rules []config.SeverityRule) (SeverityRules, error) {
+	var compiled SeverityRules
+	for i, r := range rules {
+		status, ok := event.StatusFromSeverity(r.Severity)
+		if !ok {
+			return nil, fmt.Errorf("invalid severity %q for severity rule %d, expected info, warning or critical", r.Severity, i)
+		}
+		rule := &severityRule{
+			eventTypes: r.EventTypes,
+			namespaces: r.Namespaces,
+			reasons:    r.Reasons,
+			severity:   event.SeverityFromStatus(status),
+		}
+		for _, kind := range r.Kinds {
+			rule.kinds = append(rule.kinds, strings.ToLower(kind))
+		}
+		for _, path := range r.Paths {
+			p, err := CompilePattern(path)
+			if err != nil {
+				return nil, fmt.Errorf("severity rule %d: %v", i, err)
+			}
+			rule.paths = append(rule.paths, p)
+		}
+		compiled = append(compiled, rule)
+	}
+	return compiled, nil
+}
+
+func (r *severityRule) match(s Subject) bool {
+	if len(r.kinds) > 0 && !slices.Contains(r.kinds, strings.ToLower(s.Kind)) {
+		return false
+	}
+	if len(r.eventTypes) > 0 && !slices.Contains(r.eventTypes, s.EventType) {
+		return false
+	}
+	if len(r.namespaces) > 0 && !slices.Contains(r.namespaces, s.Namespace) {
+		return false
+	}
+	if len(r.reasons) > 0 && !slices.Contains(r.reasons, s.Reason) {
+		return false
+	}
+	if len(r.paths) > 0 && !r.matchPatch(s.Patch) {
+		return false
+	}
+	return true
+}
+
+// matchPatch reports whether any changed path matches any of the path patterns
+func (r *severityRule) matchPatch(patch jsondiff.Patch) bool {
+	for _, op := range patch {
+		for _, p := range r.paths {
+			if p.Match(op.Path) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Classify returns the severity of the first rule matching the subject, false if none does
+func (rules SeverityRules) Classify(s Subject) (string, bool) {
+	for _, r := range rules {
+		if r.match(s) {
+			return r.severity, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,56 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/wI2L/jsondiff"
+)
+
+func TestCompileSeverityRules(t *testing.T) {
+	_, err := CompileSeverityRules([]config.SeverityRule{{Severity: "loud"}})
+	assert.Error(t, err)
+
+	_, err = CompileSeverityRules([]config.SeverityRule{{Paths: []string{"/spec/re:("}, Severity: "info"}})
+	assert.Error(t, err)
+
+	rules, err := CompileSeverityRules([]config.SeverityRule{{Severity: "Danger"}})
+	assert.NoError(t, err)
+	severity, ok := rules.Classify(Subject{})
+	assert.True(t, ok)
+	assert.Equal(t, "critical", severity)
+}
+
+func TestClassify(t *testing.T) {
+	rules, err := CompileSeverityRules([]config.SeverityRule{
+		{Kinds: []string{"event"}, Reasons: []string{"BackOff", "NodeNotReady"}, Severity: "critical"},
+		{Kinds: []string{"Deployment"}, EventTypes: []string{"update"}, Paths: []string{"/spec/template/spec/containers/*/image"}, Severity: "critical"},
+		{Namespaces: []string{"dev"}, Severity: "info"},
+		{Kinds: []string{"Deployment"}, EventTypes: []string{"update"}, Severity: "warning"},
+	})
+	assert.NoError(t, err)
+
+	imageChange := jsondiff.Patch{{Type: jsondiff.OperationReplace, Path: "/spec/template/spec/containers/0/image"}}
+	scale := jsondiff.Patch{{Type: jsondiff.OperationReplace, Path: "/spec/replicas"}}
+
+	var Tests = []struct {
+		name     string
+		subject  Subject
+		severity string
+		ok       bool
+	}{
+		{"event reason", Subject{Kind: "Event", EventType: "add", Reason: "BackOff"}, "critical", true},
+		{"other event reason", Subject{Kind: "Event", EventType: "add", Reason: "Pulled"}, "", false},
+		{"image change", Subject{Kind: "Deployment", EventType: "update", Namespace: "dev", Patch: imageChange}, "critical", true},
+		{"dev namespace", Subject{Kind: "Deployment", EventType: "update", Namespace: "dev", Patch: scale}, "info", true},
+		{"scale", Subject{Kind: "Deployment", EventType: "update", Namespace: "prod", Patch: scale}, "warning", true},
+		{"no match", Subject{Kind: "Deployment", EventType: "delete", Namespace: "prod"}, "", false},
+	}
+
+	for _, tt := range Tests {
+		severity, ok := rules.Classify(tt.subject)
+		assert.Equal(t, tt.ok, ok, tt.name)
+		assert.Equal(t, tt.severity, severity, tt.name)
+	}
+}
@@ -0,0 +1,257 @@
+package alertmanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/sirupsen/logrus"
+)
+
+var alertmanagerErrMsg = `
+%s
+
+You need to set the Alertmanager url
+using "url" in the alertmanager configuration or using environment variables:
+
+export KW_ALERTMANAGER_URL=http://alertmanager.monitoring:9093
+
+`
+
+const (
+	defaultResolveSeconds = 300
+	defaultResendSeconds  = 60
+
+	// the ongoing alerts expire after missing this number of resends, e.g. after a restart of statemonitor
+	resendsBeforeExpiry = 3
+)
+
+// Alertmanager handler implements handler.Handler and handler.Stopper interfaces,
+// Sends the events as alerts to the Alertmanager API v2.
+// The alerts of the changes are resolved after a delay, the alerts of the ongoing conditions
+// like NodeNotReady are sent again until their recovery
+type Alertmanager struct {
+	Url     string
+	Cluster string
+	Resolve time.Duration
+	Resend  time.Duration
+
+	client *http.Client
+	now    func() time.Time
+
+	mu sync.Mutex
+	// ongoing alerts of the conditions by incident key
+	ongoing map[string]*Alert
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// Alert is an alert of the API v2
+type Alert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	StartsAt    time.Time         `json:"startsAt"`
+	EndsAt      time.Time         `json:"endsAt"`
+}
+
+// Init prepares Alertmanager configuration and starts sending the ongoing alerts again
+func (a *Alertmanager) Init(c *config.Config) error {
+	a.Url = strings.TrimSuffix(c.Handler.Alertmanager.Url, "/")
+	a.Cluster = c.Cluster
+	a.Resolve = time.Duration(c.Handler.Alertmanager.ResolveSeconds) * time.Second
+	a.Resend = time.Duration(c.Handler.Alertmanager.ResendSeconds) * time.Second
+
+	if a.Url == "" {
+		a.Url = strings.TrimSuffix(os.Getenv("KW_ALERTMANAGER_URL"), "/")
+	}
+	if a.Resolve <= 0 {
+		a.Resolve = defaultResolveSeconds * time.Second
+	}
+	if a.Resend <= 0 {
+		a.Resend = defaultResendSeconds * time.Second
+	}
+	a.client = &http.Client{Timeout: 10 * time.Second}
+	a.now = time.Now
+	a.ongoing = map[string]*Alert{}
+
+	if a.Url == "" {
+		return fmt.Errorf(alertmanagerErrMsg, "Missing Alertmanager url")
+	}
+
+	a.stop = make(chan struct{})
+	a.done = make(chan struct{})
+	go func() {
+		defer close(a.done)
+		ticker := time.NewTicker(a.Resend)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				a.resend()
+			case <-a.stop:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops sending the ongoing alerts again, they expire after resendsBeforeExpiry resends unless another replica sends them
+func (a *Alertmanager) Stop() {
+	a.stopOnce.Do(func() {
+		close(a.stop)
+		<-a.done
+	})
+}
+
+// Handle handles an event.
+func (a *Alertmanager) Handle(e event.StatemonitorEvent) {
+	alert := a.prepareAlert(e)
+	if alert == nil {
+		return
+	}
+
+	if err := a.postAlerts([]*Alert{alert}); err != nil {
+		logrus.Errorf("Alertmanager: error sending alert %s: %v", alert.Labels["alertname"], err)
+		return
+	}
+
+	logrus.Printf("Alertmanager alert %s successfully sent at %s", alert.Labels["alertname"], time.Now())
+}
+
+// prepareAlert returns the alert of the event, resolving the alert of the condition ended by a recovery.
+// It returns nil for the recovery of a condition with no ongoing alert
+func (a *Alertmanager) prepareAlert(e event.StatemonitorEvent) *Alert {
+	now := a.now()
+	condition, recovered := e.Condition()
+	if condition == "" {
+		return &Alert{
+			Labels:      a.labels(e, e.Reason),
+			Annotations: annotations(e),
+			StartsAt:    now,
+			EndsAt:      now.Add(a.Resolve),
+		}
+	}
+
+	key := e.IncidentKey(a.Cluster)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if recovered {
+		alert, ok := a.ongoing[key]
+		if !ok {
+			logrus.Debugf("Alertmanager: no ongoing alert %s to resolve", key)
+			return nil
+		}
+		delete(a.ongoing, key)
+		resolved := *alert
+		resolved.EndsAt = now
+		return &resolved
+	}
+
+	alert := &Alert{
+		Labels:      a.labels(e, condition),
+		Annotations: annotations(e),
+		StartsAt:    now,
+		EndsAt:      now.Add(resendsBeforeExpiry * a.Resend),
+	}
+	if previous, ok := a.ongoing[key]; ok {
+		alert.StartsAt = previous.StartsAt
+	}
+	a.ongoing[key] = alert
+	sent := *alert
+	return &sent
+}
+
+// resend sends the ongoing alerts again, extending their end
+func (a *Alertmanager) resend() {
+	now := a.now()
+	a.mu.Lock()
+	var alerts []*Alert
+	for _, alert := range a.ongoing {
+		alert.EndsAt = now.Add(resendsBeforeExpiry * a.Resend)
+		sent := *alert
+		alerts = append(alerts, &sent)
+	}
+	a.mu.Unlock()
+
+	if len(alerts) == 0 {
+		return
+	}
+	if err := a.postAlerts(alerts); err != nil {
+		logrus.Errorf("Alertmanager: error sending %d ongoing alerts: %v", len(alerts), err)
+	}
+}
+
+// labels identify the alert, the empty labels being omitted
+func (a *Alertmanager) labels(e event.StatemonitorEvent, alertname string) map[string]string {
+	labels := map[string]string{}
+	for name, value := range map[string]string{
+		"alertname": alertname,
+		"namespace": e.Namespace,
+		"kind":      e.Kind,
+		"name":      e.Name,
+		"severity":  event.SeverityFromStatus(e.Status),
+		"cluster":   a.Cluster,
+	} {
+		if value != "" {
+			labels[name] = value
+		}
+	}
+	return labels
+}
+
+func annotations(e event.StatemonitorEvent) map[string]string {
+	name := e.Name
+	if e.Namespace != "" {
+		name = e.Namespace + "/" + e.Name
+	}
+	annotations := map[string]string{
+		"summary": fmt.Sprintf("%s %s %s", e.Kind, name, e.Reason),
+	}
+	if e.Details != "" {
+		annotations["description"] = e.Details
+	}
+	if len(e.Children) > 0 {
+		annotations["children"] = strings.Join(e.Children, ", ")
+	}
+	if e.Diff != "" {
+		annotations["diff"] = e.Diff
+	}
+	return annotations
+}
+
+func (a *Alertmanager) postAlerts(alerts []*Alert) error {
+	message, err := json.Marshal(alerts)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, a.Url+"/api/v2/alerts", bytes.NewBuffer(message))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, body)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package alertmanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlertmanagerInit(t *testing.T) {
+	a := &Alertmanager{}
+
+	c := &config.Config{}
+	assert.Equal(t, fmt.Errorf(alertmanagerErrMsg, "Missing Alertmanager url"), a.Init(c))
+
+	c.Handler.Alertmanager.Url = "http://alertmanager:9093/"
+	assert.NoError(t, a.Init(c))
+	assert.Equal(t, "http://alertmanager:9093", a.Url)
+	assert.Equal(t, 5*time.Minute, a.Resolve)
+	assert.Equal(t, time.Minute, a.Resend)
+
+	// the resend goroutine ends
+	a.Stop()
+	a.Stop()
+	select {
+	case <-a.done:
+	default:
+		t.Error("the resend goroutine is still running")
+	}
+}
+
+// fakeAlertmanager records the alerts posted to the API
+type fakeAlertmanager struct {
+	mu     sync.Mutex
+	alerts [][]Alert
+}
+
+func (f *fakeAlertmanager) received() [][]Alert {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]Alert{}, f.alerts...)
+}
+
+func newTestHandler(t *testing.T) (*Alertmanager, *fakeAlertmanager, *time.Time) {
+	f := &fakeAlertmanager{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v2/alerts", r.URL.Path)
+		var alerts []Alert
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&alerts))
+		f.mu.Lock()
+		f.alerts = append(f.alerts, alerts)
+		f.mu.Unlock()
+	}))
+	t.Cleanup(server.Close)
+
+	a := &Alertmanager{}
+	c := &config.Config{Cluster: "prod"}
+	c.Handler.Alertmanager = config.Alertmanager{Url: server.URL, ResendSeconds: 3600}
+	assert.NoError(t, a.Init(c))
+	t.Cleanup(a.Stop)
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	a.now = func() time.Time { return now }
+	return a, f, &now
+}
+
+func TestChangeAlert(t *testing.T) {
+	a, f, now := newTestHandler(t)
+
+	a.Handle(event.StatemonitorEvent{Kind: "Deployment", Name: "api", Namespace: "shop", Reason: "Updated", Status: "Warning", Diff: "[]"})
+
+	received := f.received()
+	assert.Len(t, received, 1)
+	alert := received[0][0]
+	assert.Equal(t, map[string]string{"alertname": "Updated", "namespace": "shop", "kind": "Deployment", "name": "api", "severity": "warning", "cluster": "prod"}, alert.Labels)
+	assert.Equal(t, map[string]string{"summary": "Deployment shop/api Updated", "diff": "[]"}, alert.Annotations)
+	assert.True(t, now.Equal(alert.StartsAt))
+	assert.True(t, now.Add(5*time.Minute).Equal(alert.EndsAt))
+}
+
+func TestConditionAlert(t *testing.T) {
+	a, f, now := newTestHandler(t)
+	started := *now
+
+	a.Handle(event.StatemonitorEvent{Kind: "Node", Name: "node-1", Reason: "NodeNotReady", Status: "Danger"})
+	*now = now.Add(time.Hour)
+	a.resend()
+	*now = now.Add(time.Minute)
+	a.Handle(event.StatemonitorEvent{Kind: "Node", Name: "node-1", Reason: "NodeReady", Status: "Normal"})
+	// recoveries without ongoing alert aren't sent
+	a.Handle(event.StatemonitorEvent{Kind: "Node", Name: "node-2", Reason: "NodeReady", Status: "Normal"})
+	a.resend()
+
+	received := f.received()
+	assert.Len(t, received, 3)
+	labels := map[string]string{"alertname": "NodeNotReady", "kind": "Node", "name": "node-1", "severity": "critical", "cluster": "prod"}
+
+	assert.Equal(t, labels, received[0][0].Labels)
+	assert.True(t, started.Add(3*time.Hour).Equal(received[0][0].EndsAt))
+
+	assert.Equal(t, labels, received[1][0].Labels)
+	assert.True(t, started.Equal(received[1][0].StartsAt))
+	assert.True(t, started.Add(4*time.Hour).Equal(received[1][0].EndsAt))
+
+	// the recovery resolves the alert with the labels it was sent with
+	assert.Equal(t, labels, received[2][0].Labels)
+	assert.True(t, now.Equal(received[2][0].EndsAt))
+}
@@ -85,13 +85,9 @@ func (m *CloudEvent) Init(c *config.Config) error {
 func (m *CloudEvent) Handle(e event.StatemonitorEvent) {
 	m.Counter++ // TODO: do we have to worry about threadsafety here?
 
-	event := cloudevents.NewEvent()
-	event.SetSource("github.com/marvasgit/kubestatewatch")
-	event.SetType("KUBERNETES_TOPOLOGY_CHANGE")
-	event.SetTime(time.Now())
-	event.SetID(fmt.Sprintf("%v-%v", m.StartTime, m.Counter))
-	if dataAssignmentError := event.SetData(cloudevents.ApplicationJSON, m.prepareMessage(e)); dataAssignmentError != nil {
-		logrus.Printf("Failed to set data: %v", dataAssignmentError)
+	event, err := NewEvent(e, fmt.Sprintf("%v-%v", m.StartTime, m.Counter))
+	if err != nil {
+		logrus.Printf("Failed to set data: %v", err)
 		return
 	}
 
@@ -104,9 +100,20 @@ func (m *CloudEvent) Handle(e event.StatemonitorEvent) {
 	logrus.Printf("Message successfully sent to %s at %s ", m.Url, time.Now())
 }
 
-func (m *CloudEvent) prepareMessage(e event.StatemonitorEvent) *CloudEventMessageData {
+// NewEvent returns the cloud event of an event with the given id, shared by the handlers sending cloud events
+func NewEvent(e event.StatemonitorEvent, id string) (cloudevents.Event, error) {
+	event := cloudevents.NewEvent()
+	event.SetSource("github.com/marvasgit/kubestatewatch")
+	event.SetType("KUBERNETES_TOPOLOGY_CHANGE")
+	event.SetTime(time.Now())
+	event.SetID(id)
+	err := event.SetData(cloudevents.ApplicationJSON, prepareMessage(e))
+	return event, err
+}
+
+func prepareMessage(e event.StatemonitorEvent) *CloudEventMessageData {
 	return &CloudEventMessageData{
-		Operation:   m.formatReason(e),
+		Operation:   formatReason(e),
 		Kind:        e.Kind,
 		ApiVersion:  e.ApiVersion,
 		ClusterUid:  "TODO",
@@ -115,7 +122,7 @@ func (m *CloudEvent) prepareMessage(e event.StatemonitorEvent) *CloudEventMessag
 	}
 }
 
-func (m *CloudEvent) formatReason(e event.StatemonitorEvent) string {
+func formatReason(e event.StatemonitorEvent) string {
 	switch e.Reason {
 	case "Created":
 		return "create"
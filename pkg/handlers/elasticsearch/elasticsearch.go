@@ -0,0 +1,314 @@
+package elasticsearch
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/marvasgit/kubestatewatch/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+var elasticsearchErrMsg = `
+%s
+
+You need to set the Elasticsearch url
+using "url" in the elasticsearch configuration or using environment variables:
+
+export KW_ELASTICSEARCH_URL=https://elasticsearch:9200
+
+`
+
+// indexTemplate maps the documents of the events, the labels being a flattened field
+//
+//go:embed index-template.json
+var indexTemplate []byte
+
+const (
+	defaultIndex        = `kubestatewatch-{{ .Time.Format "2006.01.02" }}`
+	defaultIndexPattern = "kubestatewatch-*"
+	defaultBatchSize    = 500
+	defaultFlushSeconds = 5
+	defaultRetries      = 3
+)
+
+// Elasticsearch handler implements handler.Handler, handler.Flusher and handler.Stopper interfaces,
+// Buffers the events and indexes them with the _bulk API of Elasticsearch or OpenSearch
+type Elasticsearch struct {
+	Url           string
+	Index         *template.Template
+	Cluster       string
+	BatchSize     int
+	FlushInterval time.Duration
+	Retries       int
+
+	username string
+	password string
+	apiKey   string
+	client   *http.Client
+	now      func() time.Time
+	batcher  *utils.Batcher[*document]
+}
+
+// document is a buffered event, its id making the retries of its creation idempotent
+type document struct {
+	id     string
+	index  string
+	source []byte
+}
+
+// bulkResponse is the response of the _bulk API, with an item per action
+type bulkResponse struct {
+	Errors bool                          `json:"errors"`
+	Items  []map[string]bulkItemResponse `json:"items"`
+}
+
+type bulkItemResponse struct {
+	Status int `json:"status"`
+	Error  struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+// Init prepares Elasticsearch configuration, installs the index template and starts flushing the buffered events
+func (es *Elasticsearch) Init(c *config.Config) error {
+	conf := c.Handler.Elasticsearch
+	es.Url = strings.TrimSuffix(conf.Url, "/")
+	es.Cluster = c.Cluster
+	es.BatchSize = conf.BatchSize
+	es.FlushInterval = time.Duration(conf.FlushSeconds) * time.Second
+	es.Retries = conf.Retries
+	es.username = conf.Username
+	es.password = conf.Password
+	es.apiKey = conf.ApiKey
+
+	if es.Url == "" {
+		es.Url = strings.TrimSuffix(os.Getenv("KW_ELASTICSEARCH_URL"), "/")
+	}
+	if es.apiKey == "" {
+		es.apiKey = os.Getenv("KW_ELASTICSEARCH_API_KEY")
+	}
+	if es.BatchSize <= 0 {
+		es.BatchSize = defaultBatchSize
+	}
+	if es.FlushInterval <= 0 {
+		es.FlushInterval = defaultFlushSeconds * time.Second
+	}
+	if es.Retries <= 0 {
+		es.Retries = defaultRetries
+	}
+	if es.Url == "" {
+		return fmt.Errorf(elasticsearchErrMsg, "Missing Elasticsearch url")
+	}
+
+	index := conf.Index
+	if index == "" {
+		index = defaultIndex
+	}
+	var err error
+	if es.Index, err = template.New("index").Option("missingkey=error").Parse(index); err != nil {
+		return fmt.Errorf("invalid index template: %v", err)
+	}
+
+	tlsConfig, err := utils.TLSConfig(conf.TLS)
+	if err != nil {
+		return fmt.Errorf("invalid TLS configuration: %v", err)
+	}
+	es.client = &http.Client{Timeout: 30 * time.Second, Transport: &http.Transport{Proxy: http.ProxyFromEnvironment, TLSClientConfig: tlsConfig}}
+	es.now = time.Now
+
+	if conf.InstallTemplate {
+		pattern := conf.IndexPattern
+		if pattern == "" {
+			pattern = defaultIndexPattern
+		}
+		if err := es.installTemplate(pattern); err != nil {
+			logrus.Errorf("Elasticsearch: error installing the index template: %v", err)
+		}
+	}
+
+	es.batcher = &utils.Batcher[*document]{
+		Name:      "Elasticsearch",
+		BatchSize: es.BatchSize,
+		Interval:  es.FlushInterval,
+		Retries:   es.Retries,
+		Send:      es.bulk,
+	}
+	es.batcher.Start()
+	return nil
+}
+
+// Handle handles an event.
+func (es *Elasticsearch) Handle(e event.StatemonitorEvent) {
+	doc, err := es.prepareDocument(e)
+	if err != nil {
+		logrus.Errorf("Elasticsearch: error preparing document of %s %s/%s: %v", e.Kind, e.Namespace, e.Name, err)
+		return
+	}
+	es.batcher.Add(doc)
+}
+
+func (es *Elasticsearch) prepareDocument(e event.StatemonitorEvent) (*document, error) {
+	now := es.now().UTC()
+	record := event.NewRecord(e, es.Cluster, now)
+
+	var index bytes.Buffer
+	if err := es.Index.Execute(&index, record); err != nil {
+		return nil, fmt.Errorf("error executing index template: %v", err)
+	}
+	source, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	return &document{
+		id:     fmt.Sprintf("%s/%d", e.IncidentKey(es.Cluster), now.UnixNano()),
+		index:  index.String(),
+		source: source,
+	}, nil
+}
+
+// Flush indexes the buffered events, in bulk requests of BatchSize. The items rejected with a 429 or 5xx status
+// stay buffered to be retried by the next flush, the others are dropped
+func (es *Elasticsearch) Flush() {
+	es.batcher.Flush()
+}
+
+// Stop stops flushing the buffered events periodically and indexes them
+func (es *Elasticsearch) Stop() {
+	es.batcher.Stop()
+}
+
+// bulk sends the documents in a bulk request, returning the documents to retry
+func (es *Elasticsearch) bulk(docs []*document) ([]*document, error) {
+	var body bytes.Buffer
+	for _, doc := range docs {
+		action, err := json.Marshal(map[string]interface{}{"create": map[string]string{"_index": doc.index, "_id": doc.id}})
+		if err != nil {
+			return nil, err
+		}
+		body.Write(action)
+		body.WriteByte('\n')
+		body.Write(doc.source)
+		body.WriteByte('\n')
+	}
+
+	resp, err := es.do(http.MethodPost, "/_bulk", "application/x-ndjson", &body)
+	if utils.IsRetryable(err) {
+		return docs, err
+	} else if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result bulkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return docs, fmt.Errorf("invalid bulk response: %v", err)
+	}
+	if !result.Errors {
+		logrus.Printf("Elasticsearch: %d events successfully indexed at %s", len(docs), time.Now())
+		return nil, nil
+	}
+	if len(result.Items) != len(docs) {
+		return docs, fmt.Errorf("bulk response with %d items for %d documents", len(result.Items), len(docs))
+	}
+
+	var failed []*document
+	indexed := 0
+	for i, item := range result.Items {
+		for _, r := range item {
+			switch {
+			case r.Status >= 200 && r.Status <= 299, r.Status == http.StatusConflict:
+				// a conflict is a document already created by a previous attempt
+				indexed++
+			case utils.RetryableStatus(r.Status):
+				failed = append(failed, docs[i])
+			default:
+				logrus.Errorf("Elasticsearch: event %s rejected with status %d: %s: %s", docs[i].id, r.Status, r.Error.Type, r.Error.Reason)
+			}
+		}
+	}
+	logrus.Printf("Elasticsearch: %d events successfully indexed at %s", indexed, time.Now())
+	return failed, nil
+}
+
+// installTemplate creates or updates the index template of the events,
+// mapping the labels with the flat_object type of OpenSearch instead of flattened
+func (es *Elasticsearch) installTemplate(pattern string) error {
+	resp, err := es.do(http.MethodGet, "/", "", nil)
+	if err != nil {
+		return err
+	}
+	var info struct {
+		Version struct {
+			Distribution string `json:"distribution"`
+		} `json:"version"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&info)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("invalid cluster info: %v", err)
+	}
+
+	definition := indexTemplate
+	if info.Version.Distribution == "opensearch" {
+		definition = bytes.Replace(definition, []byte(`"type": "flattened"`), []byte(`"type": "flat_object"`), 1)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(definition, &body); err != nil {
+		return err
+	}
+	body["index_patterns"] = []string{pattern}
+	message, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	resp, err = es.do(http.MethodPut, "/_index_template/kubestatewatch", "application/json", bytes.NewBuffer(message))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// do sends an authenticated request, returning an error for the non 2xx responses,
+// retryable for the network errors and the 429 or 5xx statuses
+func (es *Elasticsearch) do(method, path, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, es.Url+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Add("Content-Type", contentType)
+	}
+	if es.apiKey != "" {
+		req.Header.Add("Authorization", "ApiKey "+es.apiKey)
+	} else if es.username != "" {
+		req.SetBasicAuth(es.username, es.password)
+	}
+
+	resp, err := es.client.Do(req)
+	if err != nil {
+		return nil, utils.RetryableError{Err: err}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		err := fmt.Errorf("unexpected status %s: %s", resp.Status, message)
+		if utils.RetryableStatus(resp.StatusCode) {
+			return nil, utils.RetryableError{Err: err}
+		}
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,209 @@
+package elasticsearch
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/marvasgit/kubestatewatch/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestElasticsearchInit(t *testing.T) {
+	es := &Elasticsearch{}
+
+	c := &config.Config{}
+	assert.Equal(t, fmt.Errorf(elasticsearchErrMsg, "Missing Elasticsearch url"), es.Init(c))
+
+	c.Handler.Elasticsearch = config.Elasticsearch{Url: "http://elasticsearch:9200", Index: "kubestatewatch-{{ .Time"}
+	assert.ErrorContains(t, es.Init(c), "invalid index template")
+
+	c.Handler.Elasticsearch = config.Elasticsearch{Url: "http://elasticsearch:9200/"}
+	assert.NoError(t, es.Init(c))
+	assert.Equal(t, "http://elasticsearch:9200", es.Url)
+	assert.Equal(t, 500, es.BatchSize)
+	assert.Equal(t, 5*time.Second, es.FlushInterval)
+	assert.Equal(t, 3, es.Retries)
+}
+
+// fakeElasticsearch answers the bulk requests with status, or with the statuses of the items by document id, 201 by default
+type fakeElasticsearch struct {
+	mu       sync.Mutex
+	status   int
+	statuses map[string]int
+	// actions and documents received by bulk request
+	actions   [][]map[string]map[string]string
+	documents [][]event.Record
+	templates []map[string]interface{}
+}
+
+func (f *fakeElasticsearch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "ApiKey secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	switch r.URL.Path {
+	case "/":
+		fmt.Fprint(w, `{"version":{"distribution":"opensearch","number":"2.11.0"}}`)
+	case "/_index_template/kubestatewatch":
+		var template map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.templates = append(f.templates, template)
+	case "/_bulk":
+		if f.status != 0 {
+			w.WriteHeader(f.status)
+			return
+		}
+		var actions []map[string]map[string]string
+		var documents []event.Record
+		response := bulkResponse{}
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var action map[string]map[string]string
+			if err := json.Unmarshal(scanner.Bytes(), &action); err != nil || !scanner.Scan() {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			var document event.Record
+			if err := json.Unmarshal(scanner.Bytes(), &document); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			actions = append(actions, action)
+			documents = append(documents, document)
+
+			status, ok := f.statuses[action["create"]["_id"]]
+			if !ok {
+				status = http.StatusCreated
+			}
+			response.Errors = response.Errors || status > 299
+			response.Items = append(response.Items, map[string]bulkItemResponse{"create": {Status: status}})
+		}
+		f.actions = append(f.actions, actions)
+		f.documents = append(f.documents, documents)
+		json.NewEncoder(w).Encode(response)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestHandler(t *testing.T, f *fakeElasticsearch) *Elasticsearch {
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	es := &Elasticsearch{}
+	c := &config.Config{Cluster: "prod"}
+	c.Handler.Elasticsearch = config.Elasticsearch{Url: server.URL, ApiKey: "secret", FlushSeconds: 3600, InstallTemplate: true}
+	require.NoError(t, es.Init(c))
+	t.Cleanup(es.Stop)
+	es.now = func() time.Time { return time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC) }
+	return es
+}
+
+func TestInstallTemplate(t *testing.T) {
+	f := &fakeElasticsearch{}
+	newTestHandler(t, f)
+
+	require.Len(t, f.templates, 1)
+	assert.Equal(t, []interface{}{"kubestatewatch-*"}, f.templates[0]["index_patterns"])
+	mappings := f.templates[0]["template"].(map[string]interface{})["mappings"].(map[string]interface{})
+	assert.Equal(t, "flat_object", mappings["properties"].(map[string]interface{})["labels"].(map[string]interface{})["type"])
+}
+
+func TestBulk(t *testing.T) {
+	f := &fakeElasticsearch{statuses: map[string]int{}}
+	es := newTestHandler(t, f)
+
+	events := []event.StatemonitorEvent{
+		{Kind: "Deployment", Name: "created", Namespace: "shop", Reason: "Updated", Status: "Warning", Diff: `[{"op":"replace","path":"/spec/replicas","value":3}]`,
+			Labels: map[string]string{"app": "api", "app.kubernetes.io/name": "api"}, Actor: "helm"},
+		{Kind: "Deployment", Name: "throttled", Namespace: "shop", Reason: "Updated"},
+		{Kind: "Deployment", Name: "invalid", Namespace: "shop", Reason: "Updated"},
+		{Kind: "Deployment", Name: "conflict", Namespace: "shop", Reason: "Updated"},
+	}
+	ids := map[string]string{}
+	for _, e := range events {
+		ids[e.Name] = fmt.Sprintf("prod/Deployment/shop/%s/%d", e.Name, es.now().UnixNano())
+	}
+	f.statuses[ids["throttled"]] = http.StatusTooManyRequests
+	f.statuses[ids["invalid"]] = http.StatusBadRequest
+	f.statuses[ids["conflict"]] = http.StatusConflict
+
+	for _, e := range events {
+		es.Handle(e)
+	}
+	es.Flush()
+
+	require.Len(t, f.actions, 1)
+	assert.Equal(t, map[string]string{"_index": "kubestatewatch-2024.01.01", "_id": ids["created"]}, f.actions[0][0]["create"])
+	document := f.documents[0][0]
+	assert.Equal(t, "prod", document.Cluster)
+	assert.Equal(t, "helm", document.Actor)
+	assert.Equal(t, map[string]string{"app": "api", "app.kubernetes.io/name": "api"}, document.Labels)
+	assert.JSONEq(t, `[{"op":"replace","path":"/spec/replicas","value":3}]`, string(document.Diff))
+
+	// only the throttled item is retried, with the same id
+	delete(f.statuses, ids["throttled"])
+	es.Flush()
+	require.Len(t, f.actions, 2)
+	require.Len(t, f.actions[1], 1)
+	assert.Equal(t, ids["throttled"], f.actions[1][0]["create"]["_id"])
+
+	es.Flush()
+	assert.Len(t, f.actions, 2)
+}
+
+func TestBulkErrors(t *testing.T) {
+	f := &fakeElasticsearch{status: http.StatusServiceUnavailable}
+	es := newTestHandler(t, f)
+	docs := []*document{{id: "prod/Node//node-1/1", index: "kubestatewatch-2024.01.01", source: []byte(`{}`)}}
+
+	failed, err := es.bulk(docs)
+	assert.True(t, utils.IsRetryable(err))
+	assert.Equal(t, docs, failed)
+
+	// the requests rejected as invalid aren't retried
+	f.status = http.StatusBadRequest
+	failed, err = es.bulk(docs)
+	assert.Error(t, err)
+	assert.False(t, utils.IsRetryable(err))
+	assert.Empty(t, failed)
+}
+
+func TestBulkChunks(t *testing.T) {
+	f := &fakeElasticsearch{}
+	es := newTestHandler(t, f)
+	es.batcher.BatchSize = 2
+
+	// buffered while the cluster was unavailable
+	f.status = http.StatusServiceUnavailable
+	for _, name := range []string{"web-1", "web-2", "web-3"} {
+		es.Handle(event.StatemonitorEvent{Kind: "Pod", Name: name, Namespace: "default", Reason: "Created"})
+	}
+	es.Flush()
+
+	f.mu.Lock()
+	f.status = 0
+	f.mu.Unlock()
+	es.Flush()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	require.Len(t, f.actions, 2)
+	assert.Len(t, f.actions[0], 2)
+	assert.Len(t, f.actions[1], 1)
+}
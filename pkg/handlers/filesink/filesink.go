@@ -0,0 +1,241 @@
+package filesink
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultPath       = "/var/log/kubestatewatch/events.jsonl"
+	defaultMaxSizeMB  = 100
+	defaultMaxBackups = 10
+
+	// backupTimeFormat is the time of the rotation in the name of the rotated files, sorting them by age
+	backupTimeFormat = "2006-01-02T15-04-05.000"
+)
+
+// File handler implements handler.Handler and handler.Flusher interfaces,
+// Appends each event as a JSON line to a file, rotated by size and age.
+// The rotated files are named after the time of their rotation, e.g. events-2024-01-01T12-00-00.000.jsonl.gz
+type File struct {
+	Path       string
+	MaxSize    int64
+	RotateAge  time.Duration
+	MaxBackups int
+	Compress   bool
+	Cluster    string
+
+	now func() time.Time
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	// rotated files compressed and cleaned up in order by a background goroutine
+	rotated chan string
+	// pending rotated files
+	pending sync.WaitGroup
+}
+
+// Init prepares file configuration and opens the file
+func (f *File) Init(c *config.Config) error {
+	conf := c.Handler.File
+	f.Path = conf.Path
+	f.MaxSize = int64(conf.MaxSizeMB) << 20
+	f.RotateAge = time.Duration(conf.RotateHours) * time.Hour
+	f.MaxBackups = conf.MaxBackups
+	f.Compress = conf.Compress
+	f.Cluster = c.Cluster
+
+	if f.Path == "" {
+		f.Path = os.Getenv("KW_FILE_PATH")
+	}
+	if f.Path == "" {
+		f.Path = defaultPath
+	}
+	if f.MaxSize <= 0 {
+		f.MaxSize = defaultMaxSizeMB << 20
+	}
+	if f.MaxBackups <= 0 {
+		f.MaxBackups = defaultMaxBackups
+	}
+	f.now = time.Now
+	f.rotated = make(chan string, 16)
+
+	go func() {
+		for backup := range f.rotated {
+			if f.Compress {
+				if err := compress(backup); err != nil {
+					logrus.Errorf("File: error compressing %s: %v", backup, err)
+				}
+			}
+			f.removeBackups()
+			f.pending.Done()
+		}
+	}()
+
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0o755); err != nil {
+		return err
+	}
+	return f.open()
+}
+
+// Handle handles an event.
+func (f *File) Handle(e event.StatemonitorEvent) {
+	line, err := json.Marshal(event.NewRecord(e, f.Cluster, f.now()))
+	if err != nil {
+		logrus.Errorf("File: error encoding %s %s/%s: %v", e.Kind, e.Namespace, e.Name, err)
+		return
+	}
+	line = append(line, '\n')
+
+	if err := f.write(line); err != nil {
+		logrus.Errorf("File: error writing %s %s/%s to %s: %v", e.Kind, e.Namespace, e.Name, f.Path, err)
+	}
+}
+
+// Flush syncs the file and waits for the compression of the rotated files
+func (f *File) Flush() {
+	f.mu.Lock()
+	if f.file != nil {
+		if err := f.file.Sync(); err != nil {
+			logrus.Errorf("File: error syncing %s: %v", f.Path, err)
+		}
+	}
+	f.mu.Unlock()
+	f.pending.Wait()
+}
+
+func (f *File) write(line []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return err
+		}
+	}
+	if f.size > 0 && (f.size+int64(len(line)) > f.MaxSize || (f.RotateAge > 0 && f.now().Sub(f.openedAt) >= f.RotateAge)) {
+		if err := f.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := f.file.Write(line)
+	f.size += int64(n)
+	return err
+}
+
+// open opens the file for appending, the age of an existing file being counted from its last modification
+func (f *File) open() error {
+	file, err := os.OpenFile(f.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = f.now()
+	if f.size > 0 {
+		f.openedAt = info.ModTime()
+	}
+	return nil
+}
+
+// rotate renames the file after the time of the rotation and opens a new file,
+// the rotated file being compressed and the oldest rotated files removed in the background
+func (f *File) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	ext := filepath.Ext(f.Path)
+	backup := fmt.Sprintf("%s-%s%s", strings.TrimSuffix(f.Path, ext), f.now().UTC().Format(backupTimeFormat), ext)
+	if err := os.Rename(f.Path, backup); err != nil {
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	f.pending.Add(1)
+	f.rotated <- backup
+	return nil
+}
+
+// compress gzips the file, removing it once compressed
+func compress(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(name+".gz.tmp", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(name+".gz.tmp", name+".gz"); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
+
+// removeBackups removes the oldest rotated files beyond MaxBackups
+func (f *File) removeBackups() {
+	ext := filepath.Ext(f.Path)
+	prefix := strings.TrimSuffix(f.Path, ext) + "-"
+	matches, err := filepath.Glob(prefix + "*")
+	if err != nil {
+		logrus.Errorf("File: error listing the rotated files of %s: %v", f.Path, err)
+		return
+	}
+
+	var backups []string
+	for _, match := range matches {
+		name := strings.TrimSuffix(match, ".gz")
+		if !strings.HasSuffix(name, ext) {
+			continue
+		}
+		if _, err := time.Parse(backupTimeFormat, strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)); err == nil {
+			backups = append(backups, match)
+		}
+	}
+	sort.Strings(backups)
+
+	for len(backups) > f.MaxBackups {
+		if err := os.Remove(backups[0]); err != nil {
+			logrus.Errorf("File: error removing %s: %v", backups[0], err)
+		}
+		backups = backups[1:]
+	}
+}
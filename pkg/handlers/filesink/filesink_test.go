@@ -0,0 +1,130 @@
+package filesink
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestHandler(t *testing.T, conf config.File) (*File, *time.Time) {
+	f := &File{}
+	c := &config.Config{Cluster: "prod"}
+	c.Handler.File = conf
+	require.NoError(t, f.Init(c))
+	t.Cleanup(func() {
+		f.Flush()
+		f.file.Close()
+	})
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	f.now = func() time.Time { return now }
+	return f, &now
+}
+
+// readLines returns the records of the file, gzipped or not
+func readLines(t *testing.T, name string) []event.Record {
+	file, err := os.Open(name)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(name, ".gz") {
+		gz, err := gzip.NewReader(file)
+		require.NoError(t, err)
+		r = gz
+	}
+	var records []event.Record
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var record event.Record
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func files(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestFileInit(t *testing.T) {
+	dir := t.TempDir()
+	f, _ := newTestHandler(t, config.File{Path: filepath.Join(dir, "audit", "events.jsonl")})
+
+	assert.Equal(t, int64(100<<20), f.MaxSize)
+	assert.Equal(t, 10, f.MaxBackups)
+	assert.FileExists(t, filepath.Join(dir, "audit", "events.jsonl"))
+}
+
+func TestAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(`{"kind":"Pod","name":"old"}`+"\n"), 0o644))
+
+	f, _ := newTestHandler(t, config.File{Path: path})
+	f.Handle(event.StatemonitorEvent{Kind: "Deployment", Name: "api", Namespace: "shop", Reason: "Updated", Diff: `[{"op":"replace","path":"/spec/replicas","value":3}]`})
+	f.Flush()
+
+	records := readLines(t, path)
+	require.Len(t, records, 2)
+	assert.Equal(t, "old", records[0].Name)
+	assert.Equal(t, "prod", records[1].Cluster)
+	assert.Equal(t, "api", records[1].Name)
+	assert.JSONEq(t, `[{"op":"replace","path":"/spec/replicas","value":3}]`, string(records[1].Diff))
+}
+
+func TestRotateSize(t *testing.T) {
+	dir := t.TempDir()
+	f, now := newTestHandler(t, config.File{Path: filepath.Join(dir, "events.jsonl"), MaxBackups: 2, Compress: true})
+	e := event.StatemonitorEvent{Kind: "Pod", Name: "web", Namespace: "default", Reason: "Created"}
+	line, err := json.Marshal(event.NewRecord(e, "prod", *now))
+	require.NoError(t, err)
+	// two lines per file
+	f.MaxSize = int64(2*(len(line)+1) + 1)
+
+	for i := 0; i < 7; i++ {
+		*now = now.Add(time.Second)
+		f.Handle(e)
+	}
+	f.Flush()
+
+	// rotated before the 3rd, 5th and 7th lines, the oldest rotated file being removed
+	assert.Equal(t, []string{"events-2024-01-01T12-00-05.000.jsonl.gz", "events-2024-01-01T12-00-07.000.jsonl.gz", "events.jsonl"}, files(t, dir))
+	assert.Len(t, readLines(t, filepath.Join(dir, "events-2024-01-01T12-00-05.000.jsonl.gz")), 2)
+	assert.Len(t, readLines(t, filepath.Join(dir, "events.jsonl")), 1)
+}
+
+func TestRotateAge(t *testing.T) {
+	dir := t.TempDir()
+	f, now := newTestHandler(t, config.File{Path: filepath.Join(dir, "events.jsonl"), RotateHours: 24})
+	f.openedAt = *now
+
+	f.Handle(event.StatemonitorEvent{Kind: "Pod", Name: "web", Namespace: "default", Reason: "Created"})
+	*now = now.Add(23 * time.Hour)
+	f.Handle(event.StatemonitorEvent{Kind: "Pod", Name: "web", Namespace: "default", Reason: "Updated"})
+	*now = now.Add(time.Hour)
+	f.Handle(event.StatemonitorEvent{Kind: "Pod", Name: "web", Namespace: "default", Reason: "Deleted"})
+	f.Flush()
+
+	assert.Equal(t, []string{"events-2024-01-02T12-00-00.000.jsonl", "events.jsonl"}, files(t, dir))
+	assert.Len(t, readLines(t, filepath.Join(dir, "events-2024-01-02T12-00-00.000.jsonl")), 2)
+	assert.Equal(t, "Deleted", readLines(t, filepath.Join(dir, "events.jsonl"))[0].Reason)
+}
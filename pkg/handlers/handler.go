@@ -17,17 +17,35 @@ limitations under the License.
 package handlers
 
 import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
 	"github.com/marvasgit/kubestatewatch/config"
 	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers/alertmanager"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers/elasticsearch"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers/filesink"
 	"github.com/marvasgit/kubestatewatch/pkg/handlers/flock"
 	"github.com/marvasgit/kubestatewatch/pkg/handlers/hipchat"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers/kafka"
 	"github.com/marvasgit/kubestatewatch/pkg/handlers/lark"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers/loki"
 	"github.com/marvasgit/kubestatewatch/pkg/handlers/mattermost"
 	"github.com/marvasgit/kubestatewatch/pkg/handlers/msteam"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers/nats"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers/opsgenie"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers/pagerduty"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers/s3"
 	"github.com/marvasgit/kubestatewatch/pkg/handlers/slack"
 	"github.com/marvasgit/kubestatewatch/pkg/handlers/slackwebhook"
 	"github.com/marvasgit/kubestatewatch/pkg/handlers/smtpClient"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers/splunk"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers/syslog"
 	"github.com/marvasgit/kubestatewatch/pkg/handlers/webhook"
+	"github.com/sirupsen/logrus"
 )
 
 // Handler is implemented by any handler.
@@ -37,31 +55,70 @@ type Handler interface {
 	Handle(e event.StatemonitorEvent)
 }
 
+// Flusher is implemented by the handlers buffering events,
+// Flush sends the buffered events before the process terminates
+type Flusher interface {
+	Flush()
+}
+
+// Stopper is implemented by the handlers running background goroutines,
+// Stop ends them and sends the buffered events before the process terminates
+type Stopper interface {
+	Stop()
+}
+
 // Map maps each event handler function to a name for easily lookup
 var Map = map[string]interface{}{
-	"default":      &Default{},
-	"slack":        &slack.Slack{},
-	"slackwebhook": &slackwebhook.SlackWebhook{},
-	"hipchat":      &hipchat.Hipchat{},
-	"mattermost":   &mattermost.Mattermost{},
-	"flock":        &flock.Flock{},
-	"webhook":      &webhook.Webhook{},
-	"ms-teams":     &msteam.MSTeams{},
-	"smtp":         &smtpClient.SMTP{},
-	"lark":         &lark.Webhook{},
+	"default":       &Default{},
+	"slack":         &slack.Slack{},
+	"slackwebhook":  &slackwebhook.SlackWebhook{},
+	"hipchat":       &hipchat.Hipchat{},
+	"mattermost":    &mattermost.Mattermost{},
+	"flock":         &flock.Flock{},
+	"webhook":       &webhook.Webhook{},
+	"msteams":       &msteam.MSTeams{},
+	"smtp":          &smtpClient.SMTP{},
+	"lark":          &lark.Webhook{},
+	"pagerduty":     &pagerduty.PagerDuty{},
+	"opsgenie":      &opsgenie.Opsgenie{},
+	"alertmanager":  &alertmanager.Alertmanager{},
+	"kafka":         &kafka.Kafka{},
+	"nats":          &nats.Nats{},
+	"elasticsearch": &elasticsearch.Elasticsearch{},
+	"loki":          &loki.Loki{},
+	"syslog":        &syslog.Syslog{},
+	"splunk":        &splunk.Splunk{},
+	"file":          &filesink.File{},
+	"s3":            &s3.S3{},
 }
 
 // Default handler implements Handler interface,
-// print each event with JSON format
+// print each event with JSON format, one line per event on stdout
 type Default struct {
+	Cluster string
+
+	mu  sync.Mutex
+	out io.Writer
 }
 
 // Init initializes handler configuration
-// Do nothing for default handler
 func (d *Default) Init(c *config.Config) error {
+	d.Cluster = c.Cluster
+	d.out = os.Stdout
 	return nil
 }
 
 // Handle handles an event.
 func (d *Default) Handle(e event.StatemonitorEvent) {
+	line, err := json.Marshal(event.NewRecord(e, d.Cluster, time.Now()))
+	if err != nil {
+		logrus.Errorf("Default: error encoding %s %s/%s: %v", e.Kind, e.Namespace, e.Name, err)
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, err := d.out.Write(append(line, '\n')); err != nil {
+		logrus.Errorf("Default: error writing %s %s/%s: %v", e.Kind, e.Namespace, e.Name, err)
+	}
 }
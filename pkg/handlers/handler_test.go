@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefault(t *testing.T) {
+	d := &Default{}
+	require.NoError(t, d.Init(&config.Config{Cluster: "prod"}))
+	var out bytes.Buffer
+	d.out = &out
+
+	d.Handle(event.StatemonitorEvent{Kind: "Deployment", Name: "api", Namespace: "shop", Reason: "Updated", Status: "Warning", Diff: `[{"op":"replace","path":"/spec/replicas","value":3}]`})
+	d.Handle(event.StatemonitorEvent{Kind: "Node", Name: "node-1", Reason: "NodeNotReady", Status: "Danger", Details: "kubelet stopped\nposting status"})
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+
+	var record event.Record
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	assert.Equal(t, "prod", record.Cluster)
+	assert.Equal(t, "Deployment", record.Kind)
+	assert.Equal(t, event.SeverityWarning, record.Severity)
+	assert.JSONEq(t, `[{"op":"replace","path":"/spec/replicas","value":3}]`, string(record.Diff))
+
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &record))
+	assert.Equal(t, "kubelet stopped\nposting status", record.Details)
+}
@@ -0,0 +1,235 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers/cloudevent"
+	"github.com/marvasgit/kubestatewatch/pkg/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+)
+
+var kafkaErrMsg = `
+%s
+
+You need to set the Kafka brokers
+using "brokers" in the kafka configuration or using environment variables:
+
+export KW_KAFKA_BROKERS=broker-1:9092,broker-2:9092
+
+`
+
+const (
+	defaultTopic = "kubestatewatch"
+
+	formatJSON        = "json"
+	formatCloudEvents = "cloudevents"
+
+	// deliveryTimeout bounds the time a record is retried before its delivery fails
+	deliveryTimeout = 30 * time.Second
+)
+
+// Kafka handler implements handler.Handler and handler.Stopper interfaces,
+// Publishes each event to a topic, keyed by its object to keep the events of an object ordered
+type Kafka struct {
+	Brokers []string
+	Topic   *template.Template
+	Format  string
+	Cluster string
+
+	client *kgo.Client
+}
+
+// topicData is the data the topic template is executed with
+type topicData struct {
+	event.StatemonitorEvent
+	Cluster string
+}
+
+// Init prepares Kafka configuration and creates the producer
+func (k *Kafka) Init(c *config.Config) error {
+	conf := c.Handler.Kafka
+	k.Brokers = conf.Brokers
+	k.Format = strings.ToLower(conf.Format)
+	k.Cluster = c.Cluster
+
+	if len(k.Brokers) == 0 && os.Getenv("KW_KAFKA_BROKERS") != "" {
+		k.Brokers = strings.Split(os.Getenv("KW_KAFKA_BROKERS"), ",")
+	}
+	if len(k.Brokers) == 0 {
+		return fmt.Errorf(kafkaErrMsg, "Missing Kafka brokers")
+	}
+	if k.Format == "" {
+		k.Format = formatJSON
+	}
+	if k.Format != formatJSON && k.Format != formatCloudEvents {
+		return fmt.Errorf("invalid format %q, expected json or cloudevents", conf.Format)
+	}
+
+	topic := conf.Topic
+	if topic == "" {
+		topic = defaultTopic
+	}
+	var err error
+	if k.Topic, err = template.New("topic").Option("missingkey=error").Parse(topic); err != nil {
+		return fmt.Errorf("invalid topic template: %v", err)
+	}
+
+	opts, err := producerOptions(conf)
+	if err != nil {
+		return err
+	}
+	k.client, err = kgo.NewClient(append(opts, kgo.SeedBrokers(k.Brokers...))...)
+	return err
+}
+
+// producerOptions returns the options of the producer from the configuration
+func producerOptions(conf config.Kafka) ([]kgo.Opt, error) {
+	opts := []kgo.Opt{kgo.RecordDeliveryTimeout(deliveryTimeout)}
+
+	switch strings.ToLower(conf.Acks) {
+	case "", "all":
+		opts = append(opts, kgo.RequiredAcks(kgo.AllISRAcks()))
+	case "leader":
+		opts = append(opts, kgo.RequiredAcks(kgo.LeaderAck()))
+	case "none":
+		opts = append(opts, kgo.RequiredAcks(kgo.NoAck()))
+	default:
+		return nil, fmt.Errorf("invalid acks %q, expected all, leader or none", conf.Acks)
+	}
+	if conf.DisableIdempotence || (conf.Acks != "" && strings.ToLower(conf.Acks) != "all") {
+		opts = append(opts, kgo.DisableIdempotentWrite())
+	}
+	if conf.LingerMs > 0 {
+		opts = append(opts, kgo.ProducerLinger(time.Duration(conf.LingerMs)*time.Millisecond))
+	}
+	if conf.BatchMaxBytes > 0 {
+		opts = append(opts, kgo.ProducerBatchMaxBytes(int32(conf.BatchMaxBytes)))
+	}
+
+	if conf.Compression != "" {
+		codecs := map[string]kgo.CompressionCodec{
+			"none":   kgo.NoCompression(),
+			"gzip":   kgo.GzipCompression(),
+			"snappy": kgo.SnappyCompression(),
+			"lz4":    kgo.Lz4Compression(),
+			"zstd":   kgo.ZstdCompression(),
+		}
+		codec, ok := codecs[strings.ToLower(conf.Compression)]
+		if !ok {
+			return nil, fmt.Errorf("invalid compression %q, expected none, gzip, snappy, lz4 or zstd", conf.Compression)
+		}
+		opts = append(opts, kgo.ProducerBatchCompression(codec))
+	}
+
+	if conf.SASL.Mechanism != "" {
+		var mechanism sasl.Mechanism
+		switch strings.ToUpper(conf.SASL.Mechanism) {
+		case "PLAIN":
+			mechanism = plain.Auth{User: conf.SASL.Username, Pass: conf.SASL.Password}.AsMechanism()
+		case "SCRAM-SHA-256":
+			mechanism = scram.Auth{User: conf.SASL.Username, Pass: conf.SASL.Password}.AsSha256Mechanism()
+		case "SCRAM-SHA-512":
+			mechanism = scram.Auth{User: conf.SASL.Username, Pass: conf.SASL.Password}.AsSha512Mechanism()
+		default:
+			return nil, fmt.Errorf("invalid SASL mechanism %q, expected PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512", conf.SASL.Mechanism)
+		}
+		opts = append(opts, kgo.SASL(mechanism))
+	}
+
+	tlsConfig, err := utils.TLSConfig(conf.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS configuration: %v", err)
+	}
+	if tlsConfig != nil {
+		opts = append(opts, kgo.DialTLSConfig(tlsConfig))
+	}
+	return opts, nil
+}
+
+// Handle handles an event. The record is produced asynchronously, its delivery being logged
+func (k *Kafka) Handle(e event.StatemonitorEvent) {
+	r, err := k.record(e)
+	if err != nil {
+		logrus.Errorf("Kafka: error publishing %s %s/%s: %v", e.Kind, e.Namespace, e.Name, err)
+		return
+	}
+
+	k.client.Produce(context.Background(), r, func(_ *kgo.Record, err error) {
+		if err != nil {
+			logrus.Errorf("Kafka: error publishing %s %s/%s: %v", e.Kind, e.Namespace, e.Name, err)
+			return
+		}
+		logrus.Printf("Kafka message of %s %s/%s successfully sent at %s", e.Kind, e.Namespace, e.Name, time.Now())
+	})
+}
+
+// Stop waits for the delivery of the pending records, up to the delivery timeout, and closes the client
+func (k *Kafka) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+	defer cancel()
+	if err := k.client.Flush(ctx); err != nil {
+		logrus.Errorf("Kafka: error flushing the pending records: %v", err)
+	}
+	k.client.Close()
+}
+
+// Publish sends the events to their topics and waits for their acknowledgement, returning the first delivery error.
+// The records of concurrent calls are batched together
+func (k *Kafka) Publish(ctx context.Context, events ...event.StatemonitorEvent) error {
+	var records []*kgo.Record
+	for _, e := range events {
+		r, err := k.record(e)
+		if err != nil {
+			return err
+		}
+		records = append(records, r)
+	}
+	return k.client.ProduceSync(ctx, records...).FirstErr()
+}
+
+// record returns the record of the event, keyed by namespace/kind/name
+func (k *Kafka) record(e event.StatemonitorEvent) (*kgo.Record, error) {
+	var topic bytes.Buffer
+	if err := k.Topic.Execute(&topic, topicData{e, k.Cluster}); err != nil {
+		return nil, fmt.Errorf("error executing topic template: %v", err)
+	}
+
+	now := time.Now()
+	var value []byte
+	var contentType string
+	var err error
+	if k.Format == formatCloudEvents {
+		ce, ceErr := cloudevent.NewEvent(e, fmt.Sprintf("%s/%d", e.IncidentKey(k.Cluster), now.UnixNano()))
+		if err = ceErr; err == nil {
+			value, err = json.Marshal(ce)
+		}
+		contentType = "application/cloudevents+json"
+	} else {
+		value, err = json.Marshal(event.NewRecord(e, k.Cluster, now))
+		contentType = "application/json"
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &kgo.Record{
+		Topic:     topic.String(),
+		Key:       []byte(e.Namespace + "/" + e.Kind + "/" + e.Name),
+		Value:     value,
+		Headers:   []kgo.RecordHeader{{Key: "content-type", Value: []byte(contentType)}},
+		Timestamp: now,
+	}, nil
+}
@@ -0,0 +1,146 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+)
+
+func TestKafkaInit(t *testing.T) {
+	k := &Kafka{}
+
+	c := &config.Config{}
+	assert.Equal(t, fmt.Errorf(kafkaErrMsg, "Missing Kafka brokers"), k.Init(c))
+
+	c.Handler.Kafka = config.Kafka{Brokers: []string{"localhost:9092"}, Format: "avro"}
+	assert.EqualError(t, k.Init(c), `invalid format "avro", expected json or cloudevents`)
+
+	c.Handler.Kafka = config.Kafka{Brokers: []string{"localhost:9092"}, Topic: "kubestatewatch.{{ .Namespace"}
+	assert.ErrorContains(t, k.Init(c), "invalid topic template")
+
+	c.Handler.Kafka = config.Kafka{Brokers: []string{"localhost:9092"}, SASL: config.KafkaSASL{Mechanism: "GSSAPI"}}
+	assert.ErrorContains(t, k.Init(c), "invalid SASL mechanism")
+
+	c.Handler.Kafka = config.Kafka{Brokers: []string{"localhost:9092"}}
+	assert.NoError(t, k.Init(c))
+	assert.Equal(t, formatJSON, k.Format)
+}
+
+// newTestCluster starts an in-process Kafka cluster
+func newTestCluster(t *testing.T, opts ...kfake.Opt) *kfake.Cluster {
+	cluster, err := kfake.NewCluster(append(opts, kfake.NumBrokers(1))...)
+	require.NoError(t, err)
+	t.Cleanup(cluster.Close)
+	return cluster
+}
+
+func newTestHandler(t *testing.T, conf config.Kafka) *Kafka {
+	k := &Kafka{}
+	c := &config.Config{Cluster: "prod"}
+	c.Handler.Kafka = conf
+	require.NoError(t, k.Init(c))
+	t.Cleanup(k.client.Close)
+	return k
+}
+
+// consume returns the records of the topic
+func consume(t *testing.T, cluster *kfake.Cluster, topic string, count int, opts ...kgo.Opt) []*kgo.Record {
+	consumer, err := kgo.NewClient(append(opts, kgo.SeedBrokers(cluster.ListenAddrs()...), kgo.ConsumeTopics(topic))...)
+	require.NoError(t, err)
+	defer consumer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var records []*kgo.Record
+	for len(records) < count {
+		fetches := consumer.PollFetches(ctx)
+		require.NoError(t, ctx.Err())
+		records = append(records, fetches.Records()...)
+	}
+	return records
+}
+
+func TestKafkaPublish(t *testing.T) {
+	cluster := newTestCluster(t, kfake.SeedTopics(3, "kubestatewatch.prod.shop"))
+	k := newTestHandler(t, config.Kafka{
+		Brokers:  cluster.ListenAddrs(),
+		Topic:    "kubestatewatch.{{ .Cluster }}.{{ .Namespace }}",
+		LingerMs: 5,
+	})
+
+	var events []event.StatemonitorEvent
+	for i := 0; i < 10; i++ {
+		events = append(events, event.StatemonitorEvent{Kind: "Deployment", Name: "api", Namespace: "shop", Reason: "Updated", Status: "Warning",
+			Diff: fmt.Sprintf(`[{"op":"replace","path":"/spec/replicas","value":%d}]`, i)})
+	}
+	require.NoError(t, k.Publish(context.Background(), events...))
+
+	records := consume(t, cluster, "kubestatewatch.prod.shop", 10)
+	for i, r := range records {
+		// the records of an object are in one partition, in order
+		assert.Equal(t, "shop/Deployment/api", string(r.Key))
+		assert.Equal(t, records[0].Partition, r.Partition)
+		assert.Equal(t, "application/json", string(r.Headers[0].Value))
+
+		var record event.Record
+		require.NoError(t, json.Unmarshal(r.Value, &record))
+		assert.Equal(t, "prod", record.Cluster)
+		assert.Equal(t, event.SeverityWarning, record.Severity)
+		assert.JSONEq(t, fmt.Sprintf(`[{"op":"replace","path":"/spec/replicas","value":%d}]`, i), string(record.Diff))
+	}
+}
+
+func TestKafkaCloudEvents(t *testing.T) {
+	cluster := newTestCluster(t, kfake.SeedTopics(1, "kubestatewatch"), kfake.EnableSASL(), kfake.Superuser("SCRAM-SHA-256", "statemonitor", "secret"))
+	k := newTestHandler(t, config.Kafka{
+		Brokers: cluster.ListenAddrs(),
+		Format:  "cloudevents",
+		SASL:    config.KafkaSASL{Mechanism: "SCRAM-SHA-256", Username: "statemonitor", Password: "secret"},
+	})
+
+	k.Handle(event.StatemonitorEvent{Kind: "Node", Name: "node-1", Reason: "Deleted", Status: "Danger"})
+
+	records := consume(t, cluster, "kubestatewatch", 1, kgo.SASL(scram.Auth{User: "statemonitor", Pass: "secret"}.AsSha256Mechanism()))
+	assert.Equal(t, "/Node/node-1", string(records[0].Key))
+	assert.Equal(t, "application/cloudevents+json", string(records[0].Headers[0].Value))
+
+	var ce map[string]interface{}
+	require.NoError(t, json.Unmarshal(records[0].Value, &ce))
+	assert.Equal(t, "1.0", ce["specversion"])
+	assert.Equal(t, "KUBERNETES_TOPOLOGY_CHANGE", ce["type"])
+	assert.Equal(t, "delete", ce["data"].(map[string]interface{})["operation"])
+}
+
+func TestKafkaStop(t *testing.T) {
+	cluster := newTestCluster(t, kfake.SeedTopics(1, "kubestatewatch"))
+	k := newTestHandler(t, config.Kafka{Brokers: cluster.ListenAddrs(), LingerMs: 1000})
+
+	// the records are produced without waiting for their delivery, and the pending ones are flushed on termination
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		k.Handle(event.StatemonitorEvent{Kind: "Pod", Name: fmt.Sprintf("web-%d", i), Namespace: "default", Reason: "Created"})
+	}
+	assert.Less(t, time.Since(start), time.Second)
+	k.Stop()
+
+	assert.Len(t, consume(t, cluster, "kubestatewatch", 5), 5)
+}
+
+func TestKafkaDeliveryError(t *testing.T) {
+	cluster := newTestCluster(t)
+	k := newTestHandler(t, config.Kafka{Brokers: cluster.ListenAddrs(), Topic: "missing"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	assert.Error(t, k.Publish(ctx, event.StatemonitorEvent{Kind: "Pod", Name: "web", Namespace: "default", Reason: "Created"}))
+}
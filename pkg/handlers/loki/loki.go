@@ -0,0 +1,310 @@
+package loki
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/marvasgit/kubestatewatch/pkg/utils"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+var lokiErrMsg = `
+%s
+
+You need to set the Loki url
+using "url" in the loki configuration or using environment variables:
+
+export KW_LOKI_URL=http://loki-gateway.monitoring
+
+`
+
+const (
+	formatProtobuf = "protobuf"
+	formatJSON     = "json"
+
+	defaultBatchBytes   = 1 << 20
+	defaultFlushSeconds = 1
+	defaultRetries      = 3
+)
+
+// Loki handler implements handler.Handler, handler.Flusher and handler.Stopper interfaces,
+// Buffers the events and pushes them as log lines to Loki, labelled by cluster, namespace, kind, reason and severity
+type Loki struct {
+	Url           string
+	Cluster       string
+	TenantId      string
+	Format        string
+	BatchBytes    int
+	FlushInterval time.Duration
+	Retries       int
+
+	username string
+	password string
+	client   *http.Client
+	now      func() time.Time
+	batcher  *utils.Batcher[*entry]
+}
+
+// entry is a buffered log line with the labels of its stream
+type entry struct {
+	labels    map[string]string
+	timestamp time.Time
+	line      string
+}
+
+// stream is the entries sharing the same labels
+type stream struct {
+	labels  map[string]string
+	entries []*entry
+}
+
+// Init prepares Loki configuration and starts pushing the buffered events
+func (l *Loki) Init(c *config.Config) error {
+	conf := c.Handler.Loki
+	l.Url = strings.TrimSuffix(conf.Url, "/")
+	l.Cluster = c.Cluster
+	l.TenantId = conf.TenantId
+	l.Format = strings.ToLower(conf.Format)
+	l.BatchBytes = conf.BatchBytes
+	l.FlushInterval = time.Duration(conf.FlushSeconds) * time.Second
+	l.Retries = conf.Retries
+	l.username = conf.Username
+	l.password = conf.Password
+
+	if l.Url == "" {
+		l.Url = strings.TrimSuffix(os.Getenv("KW_LOKI_URL"), "/")
+	}
+	if l.TenantId == "" {
+		l.TenantId = os.Getenv("KW_LOKI_TENANT_ID")
+	}
+	if l.Format == "" {
+		l.Format = formatProtobuf
+	}
+	if l.BatchBytes <= 0 {
+		l.BatchBytes = defaultBatchBytes
+	}
+	if l.FlushInterval <= 0 {
+		l.FlushInterval = defaultFlushSeconds * time.Second
+	}
+	if l.Retries <= 0 {
+		l.Retries = defaultRetries
+	}
+	if l.Url == "" {
+		return fmt.Errorf(lokiErrMsg, "Missing Loki url")
+	}
+	if l.Format != formatProtobuf && l.Format != formatJSON {
+		return fmt.Errorf("invalid format %q, expected protobuf or json", conf.Format)
+	}
+
+	tlsConfig, err := utils.TLSConfig(conf.TLS)
+	if err != nil {
+		return fmt.Errorf("invalid TLS configuration: %v", err)
+	}
+	l.client = &http.Client{Timeout: 10 * time.Second, Transport: &http.Transport{Proxy: http.ProxyFromEnvironment, TLSClientConfig: tlsConfig}}
+	l.now = time.Now
+	l.batcher = &utils.Batcher[*entry]{
+		Name:      "Loki",
+		BatchSize: l.BatchBytes,
+		Interval:  l.FlushInterval,
+		Retries:   l.Retries,
+		Size:      func(e *entry) int { return len(e.line) },
+		Send:      l.send,
+	}
+	l.batcher.Start()
+	return nil
+}
+
+// Handle handles an event.
+func (l *Loki) Handle(e event.StatemonitorEvent) {
+	l.batcher.Add(&entry{labels: l.labels(e), timestamp: l.now(), line: logLine(e)})
+}
+
+// labels of the stream of the event, the empty labels being omitted
+func (l *Loki) labels(e event.StatemonitorEvent) map[string]string {
+	labels := map[string]string{"job": "kubestatewatch"}
+	for name, value := range map[string]string{
+		"cluster":   l.Cluster,
+		"namespace": e.Namespace,
+		"kind":      e.Kind,
+		"reason":    e.Reason,
+		"severity":  event.SeverityFromStatus(e.Status),
+	} {
+		if value != "" {
+			labels[name] = value
+		}
+	}
+	return labels
+}
+
+// logLine returns the message of the event followed by its diff
+func logLine(e event.StatemonitorEvent) string {
+	line := e.Message()
+	// the box of the changes already contains the diff
+	if e.Diff != "" && !strings.Contains(line, e.Diff) {
+		line += "\n" + e.Diff
+	}
+	return line
+}
+
+// Flush pushes the buffered events, in push requests of BatchBytes. The entries of a push request failing
+// with a 429 or 5xx status stay buffered to be retried by the next flush, the others are dropped
+func (l *Loki) Flush() {
+	l.batcher.Flush()
+}
+
+// Stop stops pushing the buffered events periodically and pushes them
+func (l *Loki) Stop() {
+	l.batcher.Stop()
+}
+
+// send pushes the entries, returning them when they can be pushed again
+func (l *Loki) send(entries []*entry) ([]*entry, error) {
+	err := l.push(streams(entries))
+	if utils.IsRetryable(err) {
+		return entries, err
+	} else if err != nil {
+		return nil, err
+	}
+	logrus.Printf("Loki: %d events successfully pushed at %s", len(entries), time.Now())
+	return nil, nil
+}
+
+// streams groups the entries by labels, in the order of their first entry
+func streams(entries []*entry) []*stream {
+	var streams []*stream
+	byLabels := map[string]*stream{}
+	for _, entry := range entries {
+		key := labelString(entry.labels)
+		s, ok := byLabels[key]
+		if !ok {
+			s = &stream{labels: entry.labels}
+			byLabels[key] = s
+			streams = append(streams, s)
+		}
+		s.entries = append(s.entries, entry)
+	}
+	return streams
+}
+
+// labelString returns the labels in the selector format of the protobuf push requests, e.g. {job="kubestatewatch", kind="Pod"}
+func labelString(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, name+"="+strconv.Quote(labels[name]))
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+func (l *Loki) push(streams []*stream) error {
+	body, contentType, err := l.encode(streams)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, l.Url+"/loki/api/v1/push", bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Content-Type", contentType)
+	if l.TenantId != "" {
+		req.Header.Add("X-Scope-OrgID", l.TenantId)
+	}
+	if l.username != "" {
+		req.SetBasicAuth(l.username, l.password)
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return utils.RetryableError{Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		err := fmt.Errorf("unexpected status %s: %s", resp.Status, message)
+		if utils.RetryableStatus(resp.StatusCode) {
+			return utils.RetryableError{Err: err}
+		}
+		return err
+	}
+	return nil
+}
+
+// encode returns the body of the push request of the streams and its content type
+func (l *Loki) encode(streams []*stream) ([]byte, string, error) {
+	if l.Format == formatJSON {
+		body, err := encodeJSON(streams)
+		return body, "application/json", err
+	}
+	return s2.EncodeSnappy(nil, encodeProtobuf(streams)), "application/x-protobuf", nil
+}
+
+// encodeJSON returns the JSON push request of the streams, the timestamps being strings of Unix epoch nanoseconds
+func encodeJSON(streams []*stream) ([]byte, error) {
+	type jsonStream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+	request := struct {
+		Streams []jsonStream `json:"streams"`
+	}{}
+	for _, s := range streams {
+		js := jsonStream{Stream: s.labels}
+		for _, entry := range s.entries {
+			js.Values = append(js.Values, [2]string{strconv.FormatInt(entry.timestamp.UnixNano(), 10), entry.line})
+		}
+		request.Streams = append(request.Streams, js)
+	}
+	return json.Marshal(request)
+}
+
+// encodeProtobuf returns the logproto.PushRequest of the streams:
+//
+//	message PushRequest { repeated StreamAdapter streams = 1; }
+//	message StreamAdapter { string labels = 1; repeated EntryAdapter entries = 2; }
+//	message EntryAdapter { google.protobuf.Timestamp timestamp = 1; string line = 2; }
+func encodeProtobuf(streams []*stream) []byte {
+	var request []byte
+	for _, s := range streams {
+		var message []byte
+		message = protowire.AppendTag(message, 1, protowire.BytesType)
+		message = protowire.AppendString(message, labelString(s.labels))
+		for _, entry := range s.entries {
+			var timestamp []byte
+			timestamp = protowire.AppendTag(timestamp, 1, protowire.VarintType)
+			timestamp = protowire.AppendVarint(timestamp, uint64(entry.timestamp.Unix()))
+			timestamp = protowire.AppendTag(timestamp, 2, protowire.VarintType)
+			timestamp = protowire.AppendVarint(timestamp, uint64(entry.timestamp.Nanosecond()))
+
+			var entryMessage []byte
+			entryMessage = protowire.AppendTag(entryMessage, 1, protowire.BytesType)
+			entryMessage = protowire.AppendBytes(entryMessage, timestamp)
+			entryMessage = protowire.AppendTag(entryMessage, 2, protowire.BytesType)
+			entryMessage = protowire.AppendString(entryMessage, entry.line)
+
+			message = protowire.AppendTag(message, 2, protowire.BytesType)
+			message = protowire.AppendBytes(message, entryMessage)
+		}
+		request = protowire.AppendTag(request, 1, protowire.BytesType)
+		request = protowire.AppendBytes(request, message)
+	}
+	return request
+}
@@ -0,0 +1,243 @@
+package loki
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/marvasgit/kubestatewatch/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestLokiInit(t *testing.T) {
+	l := &Loki{}
+
+	c := &config.Config{}
+	assert.Equal(t, fmt.Errorf(lokiErrMsg, "Missing Loki url"), l.Init(c))
+
+	c.Handler.Loki = config.Loki{Url: "http://loki:3100", Format: "msgpack"}
+	assert.EqualError(t, l.Init(c), `invalid format "msgpack", expected protobuf or json`)
+
+	c.Handler.Loki = config.Loki{Url: "http://loki:3100/"}
+	assert.NoError(t, l.Init(c))
+	assert.Equal(t, "http://loki:3100", l.Url)
+	assert.Equal(t, formatProtobuf, l.Format)
+	assert.Equal(t, 1<<20, l.BatchBytes)
+	assert.Equal(t, time.Second, l.FlushInterval)
+}
+
+// pushedEntry is an entry decoded from a push request
+type pushedEntry struct {
+	labels    string
+	timestamp time.Time
+	line      string
+}
+
+// fakeLoki decodes the push requests, answering them with status
+type fakeLoki struct {
+	mu       sync.Mutex
+	status   int
+	tenants  []string
+	requests [][]pushedEntry
+}
+
+func (f *fakeLoki) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	body, err := io.ReadAll(r.Body)
+	if r.URL.Path != "/loki/api/v1/push" || err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	var entries []pushedEntry
+	switch r.Header.Get("Content-Type") {
+	case "application/x-protobuf":
+		entries, err = decodeProtobuf(body)
+	case "application/json":
+		entries, err = decodeJSON(body)
+	default:
+		err = fmt.Errorf("unexpected content type %s", r.Header.Get("Content-Type"))
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.tenants = append(f.tenants, r.Header.Get("X-Scope-OrgID"))
+	f.requests = append(f.requests, entries)
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func decodeJSON(body []byte) ([]pushedEntry, error) {
+	var request struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		return nil, err
+	}
+	var entries []pushedEntry
+	for _, s := range request.Streams {
+		for _, value := range s.Values {
+			ns, err := strconv.ParseInt(value[0], 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, pushedEntry{labelString(s.Stream), time.Unix(0, ns), value[1]})
+		}
+	}
+	return entries, nil
+}
+
+// decodeProtobuf decodes the snappy compressed PushRequest
+func decodeProtobuf(body []byte) ([]pushedEntry, error) {
+	request, err := s2.Decode(nil, body)
+	if err != nil {
+		return nil, err
+	}
+	var entries []pushedEntry
+	err = consumeFields(request, func(_ protowire.Number, stream []byte) error {
+		var labels string
+		return consumeFields(stream, func(n protowire.Number, value []byte) error {
+			if n == 1 {
+				labels = string(value)
+				return nil
+			}
+			e := pushedEntry{labels: labels}
+			return consumeFields(value, func(n protowire.Number, value []byte) error {
+				if n == 2 {
+					e.line = string(value)
+					entries = append(entries, e)
+					return nil
+				}
+				var seconds, nanos uint64
+				if err := consumeVarints(value, &seconds, &nanos); err != nil {
+					return err
+				}
+				e.timestamp = time.Unix(int64(seconds), int64(nanos))
+				return nil
+			})
+		})
+	})
+	return entries, err
+}
+
+// consumeFields calls f with the length delimited fields of the message
+func consumeFields(message []byte, f func(protowire.Number, []byte) error) error {
+	for len(message) > 0 {
+		n, typ, length := protowire.ConsumeTag(message)
+		if length < 0 || typ != protowire.BytesType {
+			return fmt.Errorf("unexpected field %d", n)
+		}
+		message = message[length:]
+		value, length := protowire.ConsumeBytes(message)
+		if length < 0 {
+			return protowire.ParseError(length)
+		}
+		message = message[length:]
+		if err := f(n, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// consumeVarints decodes the varint fields of the message in their order
+func consumeVarints(message []byte, values ...*uint64) error {
+	for _, value := range values {
+		_, typ, length := protowire.ConsumeTag(message)
+		if length < 0 || typ != protowire.VarintType {
+			return fmt.Errorf("unexpected field")
+		}
+		message = message[length:]
+		*value, length = protowire.ConsumeVarint(message)
+		if length < 0 {
+			return protowire.ParseError(length)
+		}
+		message = message[length:]
+	}
+	return nil
+}
+
+func newTestHandler(t *testing.T, f *fakeLoki, conf config.Loki) (*Loki, *time.Time) {
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	l := &Loki{}
+	c := &config.Config{Cluster: "prod"}
+	conf.Url = server.URL
+	conf.FlushSeconds = 3600
+	c.Handler.Loki = conf
+	require.NoError(t, l.Init(c))
+	t.Cleanup(l.Stop)
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 123, time.UTC)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestPush(t *testing.T) {
+	for _, format := range []string{"protobuf", "json"} {
+		t.Run(format, func(t *testing.T) {
+			f := &fakeLoki{}
+			l, now := newTestHandler(t, f, config.Loki{TenantId: "platform", Format: format})
+
+			l.Handle(event.StatemonitorEvent{Kind: "Deployment", Name: "api", Namespace: "shop", Reason: "Updated", Status: "Warning", Diff: `[{"op":"replace","path":"/spec/replicas","value":3}]`})
+			*now = now.Add(time.Second)
+			l.Handle(event.StatemonitorEvent{Kind: "Node", Name: "node-1", Reason: "NodeNotReady", Status: "Danger", Diff: `[{"op":"replace","path":"/status/conditions/0/status","value":"False"}]`})
+			*now = now.Add(time.Second)
+			l.Handle(event.StatemonitorEvent{Kind: "Deployment", Name: "web", Namespace: "shop", Reason: "Updated", Status: "Warning"})
+			l.Flush()
+
+			require.Len(t, f.requests, 1)
+			assert.Equal(t, []string{"platform"}, f.tenants)
+			entries := f.requests[0]
+			require.Len(t, entries, 3)
+
+			// the entries are grouped by stream
+			deployments := `{cluster="prod", job="kubestatewatch", kind="Deployment", namespace="shop", reason="Updated", severity="warning"}`
+			assert.Equal(t, deployments, entries[0].labels)
+			assert.True(t, time.Date(2024, 1, 1, 12, 0, 0, 123, time.UTC).Equal(entries[0].timestamp))
+			assert.Contains(t, entries[0].line, `[{"op":"replace","path":"/spec/replicas","value":3}]`)
+			assert.Equal(t, deployments, entries[1].labels)
+			assert.Contains(t, entries[1].line, "web")
+
+			assert.Equal(t, `{cluster="prod", job="kubestatewatch", kind="Node", reason="NodeNotReady", severity="critical"}`, entries[2].labels)
+			assert.Equal(t, "Node `node-1` is Not Ready : \nNodeNotReady\n"+`[{"op":"replace","path":"/status/conditions/0/status","value":"False"}]`, entries[2].line)
+		})
+	}
+}
+
+func TestPushErrors(t *testing.T) {
+	f := &fakeLoki{status: http.StatusTooManyRequests}
+	l, _ := newTestHandler(t, f, config.Loki{})
+	entries := []*entry{{labels: map[string]string{"job": "kubestatewatch"}, timestamp: l.now(), line: "Pod web created"}}
+
+	failed, err := l.send(entries)
+	assert.True(t, utils.IsRetryable(err))
+	assert.Equal(t, entries, failed)
+
+	// the requests rejected as invalid aren't retried
+	f.status = http.StatusBadRequest
+	failed, err = l.send(entries)
+	assert.Error(t, err)
+	assert.False(t, utils.IsRetryable(err))
+	assert.Empty(t, failed)
+}
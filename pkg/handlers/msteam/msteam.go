@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/marvasgit/kubestatewatch/config"
 	"github.com/marvasgit/kubestatewatch/pkg/event"
@@ -156,6 +157,18 @@ func (ms *MSTeams) Handle(e event.StatemonitorEvent) {
 		Name:  "Status",
 		Value: e.Status,
 	})
+	if e.Details != "" {
+		s.Facts = append(s.Facts, TeamsMessageCardSectionFacts{
+			Name:  "Details",
+			Value: e.Details,
+		})
+	}
+	if len(e.Children) > 0 {
+		s.Facts = append(s.Facts, TeamsMessageCardSectionFacts{
+			Name:  "Children",
+			Value: strings.Join(e.Children, "  \n"),
+		})
+	}
 	s.Markdown = true
 	card.Text = e.Diff
 
@@ -0,0 +1,233 @@
+package nats
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/marvasgit/kubestatewatch/pkg/handlers/cloudevent"
+	"github.com/marvasgit/kubestatewatch/pkg/utils"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/sirupsen/logrus"
+)
+
+var natsErrMsg = `
+%s
+
+You need to set the NATS url
+using "url" in the nats configuration or using environment variables:
+
+export KW_NATS_URL=nats://nats:4222
+
+`
+
+const (
+	defaultSubject = "kubestatewatch.{{ .Cluster }}.{{ .Namespace }}.{{ .Kind }}.{{ .Reason }}"
+
+	// publishRetries is the number of times a message whose acknowledgement timed out is published again
+	publishRetries = 2
+)
+
+// ackTimeout bounds the wait for the acknowledgement of JetStream
+var ackTimeout = 10 * time.Second
+
+// Nats handler implements handler.Handler interface,
+// Publishes each event as a CloudEvent to a subject, to JetStream when enabled
+type Nats struct {
+	Url       string
+	Subject   *template.Template
+	JetStream bool
+	Cluster   string
+
+	conn *nats.Conn
+	js   jetstream.JetStream
+}
+
+// subjectData is the data the subject template is executed with
+type subjectData struct {
+	event.StatemonitorEvent
+	Cluster string
+}
+
+// Init prepares NATS configuration and connects to the servers
+func (n *Nats) Init(c *config.Config) error {
+	conf := c.Handler.Nats
+	n.Url = conf.Url
+	n.JetStream = conf.JetStream
+	n.Cluster = c.Cluster
+
+	if n.Url == "" {
+		n.Url = os.Getenv("KW_NATS_URL")
+	}
+	if n.Url == "" {
+		return fmt.Errorf(natsErrMsg, "Missing NATS url")
+	}
+
+	subject := conf.Subject
+	if subject == "" {
+		subject = defaultSubject
+	}
+	var err error
+	if n.Subject, err = template.New("subject").Option("missingkey=error").Parse(subject); err != nil {
+		return fmt.Errorf("invalid subject template: %v", err)
+	}
+
+	opts, err := connectOptions(conf)
+	if err != nil {
+		return err
+	}
+	if n.conn, err = nats.Connect(n.Url, opts...); err != nil {
+		return fmt.Errorf("error connecting to %s: %v", n.Url, err)
+	}
+	if n.JetStream {
+		if n.js, err = jetstream.New(n.conn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// connectOptions returns the options of the connection from the configuration
+func connectOptions(conf config.Nats) ([]nats.Option, error) {
+	opts := []nats.Option{
+		nats.Name("kubestatewatch"),
+		// keep reconnecting, the messages are buffered meanwhile
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				logrus.Errorf("NATS: disconnected: %v", err)
+			}
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			logrus.Printf("NATS: reconnected to %s", nc.ConnectedUrl())
+		}),
+	}
+
+	switch {
+	case conf.CredsFile != "":
+		opts = append(opts, nats.UserCredentials(conf.CredsFile))
+	case conf.Token != "":
+		opts = append(opts, nats.Token(conf.Token))
+	case conf.Username != "":
+		opts = append(opts, nats.UserInfo(conf.Username, conf.Password))
+	}
+
+	tlsConfig, err := utils.TLSConfig(conf.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS configuration: %v", err)
+	}
+	if tlsConfig != nil {
+		opts = append(opts, nats.Secure(tlsConfig))
+	}
+	return opts, nil
+}
+
+// Handle handles an event.
+func (n *Nats) Handle(e event.StatemonitorEvent) {
+	if err := n.Publish(context.Background(), e); err != nil {
+		logrus.Errorf("NATS: error publishing %s %s/%s: %v", e.Kind, e.Namespace, e.Name, err)
+		return
+	}
+
+	logrus.Printf("NATS message of %s %s/%s successfully sent at %s", e.Kind, e.Namespace, e.Name, time.Now())
+}
+
+// Publish sends the event to its subject, waiting for the acknowledgement of JetStream when enabled.
+// The message is published again with the same id when its acknowledgement times out, JetStream deduplicating it if it was stored
+func (n *Nats) Publish(ctx context.Context, e event.StatemonitorEvent) error {
+	msg, err := n.message(e)
+	if err != nil {
+		return err
+	}
+
+	if n.js == nil {
+		return n.conn.PublishMsg(msg)
+	}
+	for attempt := 0; ; attempt++ {
+		ackCtx, cancel := context.WithTimeout(ctx, ackTimeout)
+		ack, err := n.js.PublishMsg(ackCtx, msg)
+		cancel()
+		if err == nil {
+			if ack.Duplicate {
+				logrus.Debugf("NATS: message %s already in stream %s", msg.Header.Get(jetstream.MsgIDHeader), ack.Stream)
+			}
+			return nil
+		}
+		if !errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil || attempt == publishRetries {
+			return err
+		}
+		logrus.Printf("NATS: acknowledgement of %s timed out, publishing it again", msg.Header.Get(jetstream.MsgIDHeader))
+	}
+}
+
+// messageId identifies a publication of the event by its object, reason, content and time. The id only
+// deduplicates the message published again after a lost acknowledgement, the events without diff being distinct
+func (n *Nats) messageId(e event.StatemonitorEvent) string {
+	content := sha256.New()
+	for _, part := range append([]string{e.Details, e.Diff}, e.Children...) {
+		content.Write([]byte(part))
+		content.Write([]byte{0})
+	}
+	return fmt.Sprintf("%s/%s/%s/%d", e.IncidentKey(n.Cluster), e.Reason, hex.EncodeToString(content.Sum(nil)[:8]), time.Now().UnixNano())
+}
+
+// message returns the message of the event, its CloudEvent id being the Nats-Msg-Id deduplicating it
+func (n *Nats) message(e event.StatemonitorEvent) (*nats.Msg, error) {
+	var subject bytes.Buffer
+	if err := n.Subject.Execute(&subject, subjectData{subjectEvent(e), subjectToken(n.Cluster)}); err != nil {
+		return nil, fmt.Errorf("error executing subject template: %v", err)
+	}
+
+	id := n.messageId(e)
+	ce, err := cloudevent.NewEvent(e, id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(ce)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := nats.NewMsg(subject.String())
+	msg.Data = data
+	msg.Header.Set("Content-Type", "application/cloudevents+json")
+	msg.Header.Set(jetstream.MsgIDHeader, id)
+	return msg, nil
+}
+
+// subjectEvent returns the event with the fields used in subjects turned into valid tokens
+func subjectEvent(e event.StatemonitorEvent) event.StatemonitorEvent {
+	e.Namespace = subjectToken(e.Namespace)
+	e.Kind = subjectToken(e.Kind)
+	e.Name = subjectToken(e.Name)
+	e.Reason = subjectToken(e.Reason)
+	e.Status = subjectToken(e.Status)
+	e.ApiVersion = subjectToken(e.ApiVersion)
+	e.Route = subjectToken(e.Route)
+	return e
+}
+
+// subjectToken replaces the separators and wildcards of subjects, "_" standing for an empty value
+func subjectToken(s string) string {
+	if s == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', ' ', '\t', '\r', '\n':
+			return '_'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,154 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNatsInit(t *testing.T) {
+	n := &Nats{}
+
+	c := &config.Config{}
+	assert.Equal(t, fmt.Errorf(natsErrMsg, "Missing NATS url"), n.Init(c))
+
+	c.Handler.Nats = config.Nats{Url: "nats://localhost:4222", Subject: "kubestatewatch.{{ .Kind"}
+	assert.ErrorContains(t, n.Init(c), "invalid subject template")
+}
+
+// newTestServer starts an embedded NATS server with JetStream
+func newTestServer(t *testing.T) *server.Server {
+	s, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, JetStream: true, StoreDir: t.TempDir()})
+	require.NoError(t, err)
+	go s.Start()
+	require.True(t, s.ReadyForConnections(10*time.Second))
+	t.Cleanup(s.Shutdown)
+	return s
+}
+
+func newTestHandler(t *testing.T, conf config.Nats) *Nats {
+	n := &Nats{}
+	c := &config.Config{Cluster: "prod"}
+	c.Handler.Nats = conf
+	require.NoError(t, n.Init(c))
+	t.Cleanup(n.conn.Close)
+	return n
+}
+
+func TestNatsPublish(t *testing.T) {
+	s := newTestServer(t)
+	n := newTestHandler(t, config.Nats{Url: s.ClientURL()})
+
+	sub, err := n.conn.SubscribeSync("kubestatewatch.>")
+	require.NoError(t, err)
+	require.NoError(t, n.conn.Flush())
+
+	n.Handle(event.StatemonitorEvent{Kind: "Ingress", ApiVersion: "networking.k8s.io/v1", Name: "web", Namespace: "shop", Reason: "Deleted", Status: "Danger"})
+	n.Handle(event.StatemonitorEvent{Kind: "Node", Name: "node-1", Reason: "NodeNotReady", Status: "Danger"})
+
+	msg, err := sub.NextMsg(5 * time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "kubestatewatch.prod.shop.Ingress.Deleted", msg.Subject)
+	assert.Equal(t, "application/cloudevents+json", msg.Header.Get("Content-Type"))
+
+	var ce map[string]interface{}
+	require.NoError(t, json.Unmarshal(msg.Data, &ce))
+	assert.Equal(t, msg.Header.Get(jetstream.MsgIDHeader), ce["id"])
+	assert.Equal(t, "KUBERNETES_TOPOLOGY_CHANGE", ce["type"])
+	assert.Equal(t, "delete", ce["data"].(map[string]interface{})["operation"])
+
+	// the empty namespace of the cluster scoped objects is a "_" token
+	msg, err = sub.NextMsg(5 * time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "kubestatewatch.prod._.Node.NodeNotReady", msg.Subject)
+}
+
+func TestNatsJetStream(t *testing.T) {
+	s := newTestServer(t)
+	n := newTestHandler(t, config.Nats{Url: s.ClientURL(), Subject: "events.{{ .Namespace }}.{{ .ApiVersion }}", JetStream: true})
+
+	ctx := context.Background()
+	e := event.StatemonitorEvent{Kind: "Ingress", ApiVersion: "networking.k8s.io/v1", Name: "web", Namespace: "shop", Reason: "Created"}
+	// without stream for the subject the publication isn't acknowledged
+	assert.ErrorIs(t, n.Publish(ctx, e), jetstream.ErrNoStreamResponse)
+
+	stream, err := n.js.CreateStream(ctx, jetstream.StreamConfig{Name: "EVENTS", Subjects: []string{"events.>"}})
+	require.NoError(t, err)
+	require.NoError(t, n.Publish(ctx, e))
+
+	// a message published again, e.g. after a lost acknowledgement, is deduplicated by its Nats-Msg-Id
+	msg, err := n.message(e)
+	require.NoError(t, err)
+	assert.Equal(t, "events.shop.networking_k8s_io/v1", msg.Subject)
+	_, err = n.js.PublishMsg(ctx, msg)
+	require.NoError(t, err)
+	ack, err := n.js.PublishMsg(ctx, &nats.Msg{Subject: msg.Subject, Header: msg.Header, Data: msg.Data})
+	require.NoError(t, err)
+	assert.True(t, ack.Duplicate)
+
+	// the events without diff of the same object are distinct, e.g. Created, Deleted, Created
+	require.NoError(t, n.Publish(ctx, e))
+	e.Reason = "Deleted"
+	require.NoError(t, n.Publish(ctx, e))
+	e.Reason = "Created"
+	require.NoError(t, n.Publish(ctx, e))
+
+	info, err := stream.Info(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), info.State.Msgs)
+}
+
+func TestNatsEventsWithoutDiff(t *testing.T) {
+	s := newTestServer(t)
+	n := newTestHandler(t, config.Nats{Url: s.ClientURL(), Subject: "events.{{ .Kind }}", JetStream: true})
+
+	ctx := context.Background()
+	stream, err := n.js.CreateStream(ctx, jetstream.StreamConfig{Name: "EVENTS", Subjects: []string{"events.>"}})
+	require.NoError(t, err)
+
+	// the failures of two containers of a pod, then the same failure again
+	e := event.StatemonitorEvent{Kind: "Pod", Name: "api-7d9-x2", Namespace: "shop", Reason: "ContainerFailed", Status: "Danger", Details: "container api exited with 1"}
+	n.Handle(e)
+	e.Details = "container sidecar exited with 137"
+	n.Handle(e)
+	n.Handle(e)
+
+	info, err := stream.Info(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), info.State.Msgs)
+}
+
+func TestNatsAckTimeout(t *testing.T) {
+	ackTimeout = 100 * time.Millisecond
+	defer func() { ackTimeout = 10 * time.Second }()
+
+	s := newTestServer(t)
+	n := newTestHandler(t, config.Nats{Url: s.ClientURL(), Subject: "events.{{ .Kind }}", JetStream: true})
+
+	// the first acknowledgement is lost
+	ids := make(chan string, 3)
+	_, err := n.conn.Subscribe("events.>", func(msg *nats.Msg) {
+		ids <- msg.Header.Get(jetstream.MsgIDHeader)
+		if len(ids) > 1 {
+			assert.NoError(t, msg.Respond([]byte(`{"stream":"EVENTS","seq":1}`)))
+		}
+	})
+	require.NoError(t, err)
+	require.NoError(t, n.conn.Flush())
+
+	require.NoError(t, n.Publish(context.Background(), event.StatemonitorEvent{Kind: "Ingress", Name: "web", Namespace: "shop", Reason: "Deleted"}))
+	require.Len(t, ids, 2)
+	first, second := <-ids, <-ids
+	assert.Regexp(t, `^prod/Ingress/shop/web/Deleted/[0-9a-f]{16}/\d+$`, first)
+	assert.Equal(t, first, second)
+}
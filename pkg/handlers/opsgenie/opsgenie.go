@@ -0,0 +1,259 @@
+package opsgenie
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/marvasgit/kubestatewatch/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+var opsgenieErrMsg = `
+%s
+
+You need to set the Opsgenie API key of an API integration
+using "apiKey" in the opsgenie configuration or using environment variables:
+
+export KW_OPSGENIE_API_KEY=api_key
+
+`
+
+const (
+	defaultURL = "https://api.opsgenie.com"
+	source     = "kubestatewatch"
+
+	// limits of the Alert API
+	maxMessageLength     = 130
+	maxDescriptionLength = 15000
+	maxTags              = 20
+	maxTagLength         = 50
+)
+
+// priorities of the alerts by severity
+var priorities = map[string]string{
+	event.SeverityCritical: "P1",
+	event.SeverityWarning:  "P3",
+	event.SeverityInfo:     "P5",
+}
+
+// Opsgenie handler implements handler.Handler interface,
+// Creates an alert for each event, closed by the recovery events
+type Opsgenie struct {
+	ApiKey     string
+	Url        string
+	Cluster    string
+	Responders []config.OpsgenieResponder
+	Routes     []config.OpsgenieRoute
+	Tags       []string
+
+	client *http.Client
+}
+
+// Alert is the request creating an alert
+type Alert struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias"`
+	Description string            `json:"description,omitempty"`
+	Responders  []Responder       `json:"responders,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+	Entity      string            `json:"entity,omitempty"`
+	Source      string            `json:"source"`
+	Priority    string            `json:"priority"`
+}
+
+// Responder of an alert
+type Responder struct {
+	Type     string `json:"type"`
+	Id       string `json:"id,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+// CloseRequest is the request closing an alert
+type CloseRequest struct {
+	Source string `json:"source"`
+	Note   string `json:"note,omitempty"`
+}
+
+// Init prepares Opsgenie configuration
+func (o *Opsgenie) Init(c *config.Config) error {
+	o.ApiKey = c.Handler.Opsgenie.ApiKey
+	o.Url = strings.TrimSuffix(c.Handler.Opsgenie.Url, "/")
+	o.Cluster = c.Cluster
+	o.Responders = c.Handler.Opsgenie.Responders
+	o.Routes = c.Handler.Opsgenie.Routes
+	o.Tags = c.Handler.Opsgenie.Tags
+
+	if o.ApiKey == "" {
+		o.ApiKey = os.Getenv("KW_OPSGENIE_API_KEY")
+	}
+	if o.Url == "" {
+		o.Url = defaultURL
+	}
+	o.client = &http.Client{Timeout: 10 * time.Second}
+
+	if o.ApiKey == "" {
+		return fmt.Errorf(opsgenieErrMsg, "Missing Opsgenie API key")
+	}
+	return nil
+}
+
+// Handle handles an event.
+func (o *Opsgenie) Handle(e event.StatemonitorEvent) {
+	alias := e.IncidentKey(o.Cluster)
+
+	var err error
+	action := "create"
+	if _, recovered := e.Condition(); recovered {
+		action = "close"
+		err = o.post(fmt.Sprintf("%s/v2/alerts/%s/close?identifierType=alias", o.Url, url.PathEscape(alias)),
+			&CloseRequest{Source: source, Note: closeNote(e)})
+	} else {
+		err = o.post(o.Url+"/v2/alerts", o.prepareAlert(e, alias))
+	}
+	if err != nil {
+		logrus.Errorf("Opsgenie: error sending %s of %s: %v", action, alias, err)
+		return
+	}
+
+	logrus.Printf("Opsgenie %s of %s successfully sent at %s", action, alias, time.Now())
+}
+
+func (o *Opsgenie) prepareAlert(e event.StatemonitorEvent, alias string) *Alert {
+	entity := e.Name
+	if e.Namespace != "" {
+		entity = e.Namespace + "/" + e.Name
+	}
+	return &Alert{
+		Message:     utils.Truncate(fmt.Sprintf("%s %s %s", e.Kind, entity, e.Reason), maxMessageLength),
+		Alias:       alias,
+		Description: utils.Truncate(description(e), maxDescriptionLength),
+		Responders:  o.responders(e.Route),
+		Tags:        o.tags(e),
+		Details:     details(e, o.Cluster),
+		Entity:      entity,
+		Source:      source,
+		Priority:    priorities[event.SeverityFromStatus(e.Status)],
+	}
+}
+
+// responders returns the responders of the route the event is sent by, the default responders otherwise
+func (o *Opsgenie) responders(route string) []Responder {
+	configured := o.Responders
+	for _, r := range o.Routes {
+		if route != "" && r.Name == route {
+			configured = r.Responders
+			break
+		}
+	}
+
+	var responders []Responder
+	for _, r := range configured {
+		responders = append(responders, Responder{Type: r.Type, Id: r.Id, Name: r.Name, Username: r.Username})
+	}
+	return responders
+}
+
+// tags returns the configured tags followed by the cluster, namespace, kind and labels of the object
+func (o *Opsgenie) tags(e event.StatemonitorEvent) []string {
+	tags := append([]string{}, o.Tags...)
+	if o.Cluster != "" {
+		tags = append(tags, "cluster:"+o.Cluster)
+	}
+	if e.Namespace != "" {
+		tags = append(tags, "namespace:"+e.Namespace)
+	}
+	tags = append(tags, "kind:"+e.Kind)
+
+	labels := make([]string, 0, len(e.Labels))
+	for k, v := range e.Labels {
+		labels = append(labels, k+":"+v)
+	}
+	sort.Strings(labels)
+	tags = append(tags, labels...)
+
+	if len(tags) > maxTags {
+		tags = tags[:maxTags]
+	}
+	for i, tag := range tags {
+		tags[i] = utils.Truncate(tag, maxTagLength)
+	}
+	return tags
+}
+
+func description(e event.StatemonitorEvent) string {
+	var parts []string
+	if e.Details != "" {
+		parts = append(parts, e.Details)
+	}
+	if len(e.Children) > 0 {
+		parts = append(parts, "Children: "+strings.Join(e.Children, ", "))
+	}
+	if e.Diff != "" {
+		parts = append(parts, e.Diff)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func details(e event.StatemonitorEvent, cluster string) map[string]string {
+	d := map[string]string{
+		"kind":       e.Kind,
+		"name":       e.Name,
+		"apiVersion": e.ApiVersion,
+		"reason":     e.Reason,
+	}
+	if e.Namespace != "" {
+		d["namespace"] = e.Namespace
+	}
+	if cluster != "" {
+		d["cluster"] = cluster
+	}
+	if e.Route != "" {
+		d["route"] = e.Route
+	}
+	return d
+}
+
+func closeNote(e event.StatemonitorEvent) string {
+	if e.Details != "" {
+		return e.Reason + ": " + e.Details
+	}
+	return e.Reason
+}
+
+func (o *Opsgenie) post(url string, body interface{}) error {
+	message, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(message))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Authorization", "GenieKey "+o.ApiKey)
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, respBody)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package opsgenie
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpsgenieInit(t *testing.T) {
+	o := &Opsgenie{}
+
+	c := &config.Config{}
+	assert.Equal(t, fmt.Errorf(opsgenieErrMsg, "Missing Opsgenie API key"), o.Init(c))
+
+	c.Handler.Opsgenie.ApiKey = "key"
+	assert.NoError(t, o.Init(c))
+	assert.Equal(t, defaultURL, o.Url)
+}
+
+// request is a request received by the fake Alert API
+type request struct {
+	path          string
+	query         string
+	authorization string
+	body          map[string]interface{}
+}
+
+func newTestHandler(t *testing.T) (*Opsgenie, *[]request) {
+	var received []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := request{path: r.URL.EscapedPath(), query: r.URL.RawQuery, authorization: r.Header.Get("Authorization")}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req.body))
+		received = append(received, req)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(server.Close)
+
+	o := &Opsgenie{}
+	c := &config.Config{Cluster: "prod"}
+	c.Handler.Opsgenie = config.Opsgenie{
+		ApiKey:     "key",
+		Url:        server.URL,
+		Responders: []config.OpsgenieResponder{{Type: "team", Name: "platform"}},
+		Routes:     []config.OpsgenieRoute{{Name: "payments", Responders: []config.OpsgenieResponder{{Type: "schedule", Id: "42"}}}},
+		Tags:       []string{"k8s"},
+	}
+	assert.NoError(t, o.Init(c))
+	return o, &received
+}
+
+func TestOpsgenieCreate(t *testing.T) {
+	o, received := newTestHandler(t)
+
+	o.Handle(event.StatemonitorEvent{
+		Kind:      "Deployment",
+		Name:      "api",
+		Namespace: "shop",
+		Reason:    "Deleted",
+		Status:    "Danger",
+		Labels:    map[string]string{"team": "payments", "app": "api"},
+	})
+	o.Handle(event.StatemonitorEvent{Kind: "Deployment", Name: "api", Namespace: "shop", Reason: "Updated", Status: "Warning", Route: "payments", Diff: "[]"})
+
+	assert.Len(t, *received, 2)
+	alert := (*received)[0]
+	assert.Equal(t, "/v2/alerts", alert.path)
+	assert.Equal(t, "GenieKey key", alert.authorization)
+	assert.Equal(t, "Deployment shop/api Deleted", alert.body["message"])
+	assert.Equal(t, "prod/Deployment/shop/api", alert.body["alias"])
+	assert.Equal(t, "P1", alert.body["priority"])
+	assert.Equal(t, []interface{}{"k8s", "cluster:prod", "namespace:shop", "kind:Deployment", "app:api", "team:payments"}, alert.body["tags"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"type": "team", "name": "platform"}}, alert.body["responders"])
+
+	alert = (*received)[1]
+	assert.Equal(t, "P3", alert.body["priority"])
+	assert.Equal(t, "[]", alert.body["description"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"type": "schedule", "id": "42"}}, alert.body["responders"])
+}
+
+func TestOpsgenieClose(t *testing.T) {
+	o, received := newTestHandler(t)
+
+	o.Handle(event.StatemonitorEvent{Kind: "Node", Name: "node-1", Reason: "NodeNotReady", Status: "Danger"})
+	o.Handle(event.StatemonitorEvent{Kind: "Node", Name: "node-1", Reason: "NodeReady", Status: "Normal", Details: "Ready is True"})
+
+	assert.Len(t, *received, 2)
+	assert.Equal(t, "prod/Node//node-1/NodeNotReady", (*received)[0].body["alias"])
+	assert.Equal(t, "/v2/alerts/prod%2FNode%2F%2Fnode-1%2FNodeNotReady/close", (*received)[1].path)
+	assert.Equal(t, "identifierType=alias", (*received)[1].query)
+	assert.Equal(t, map[string]interface{}{"source": source, "note": "NodeReady: Ready is True"}, (*received)[1].body)
+}
+
+func TestTags(t *testing.T) {
+	labels := map[string]string{}
+	for i := 0; i < 30; i++ {
+		labels[fmt.Sprintf("label-%02d", i)] = "value"
+	}
+	o := &Opsgenie{}
+	tags := o.tags(event.StatemonitorEvent{Kind: "Pod", Labels: labels})
+	assert.Len(t, tags, maxTags)
+	assert.Equal(t, "kind:Pod", tags[0])
+}
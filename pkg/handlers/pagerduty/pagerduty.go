@@ -0,0 +1,191 @@
+package pagerduty
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/marvasgit/kubestatewatch/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+var pagerDutyErrMsg = `
+%s
+
+You need to set the PagerDuty routing key (the integration key of the service)
+using "routingKey" in the pagerduty configuration or using environment variables:
+
+export KW_PAGERDUTY_ROUTING_KEY=routing_key
+
+`
+
+const (
+	defaultURL = "https://events.pagerduty.com/v2/enqueue"
+
+	actionTrigger = "trigger"
+	actionResolve = "resolve"
+
+	// maxSummaryLength is the maximum length of the summary accepted by the Events API
+	maxSummaryLength = 1024
+)
+
+// PagerDuty handler implements handler.Handler interface,
+// Triggers an alert of the Events API v2 for each event, resolved by the recovery events
+type PagerDuty struct {
+	RoutingKey string
+	Url        string
+	Cluster    string
+
+	client *http.Client
+}
+
+// Event is an event of the Events API v2
+type Event struct {
+	RoutingKey  string   `json:"routing_key"`
+	EventAction string   `json:"event_action"`
+	DedupKey    string   `json:"dedup_key"`
+	Payload     *Payload `json:"payload,omitempty"`
+}
+
+// Payload describes the alert of a trigger event
+type Payload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Timestamp     string                 `json:"timestamp"`
+	Component     string                 `json:"component,omitempty"`
+	Group         string                 `json:"group,omitempty"`
+	Class         string                 `json:"class,omitempty"`
+	CustomDetails map[string]interface{} `json:"custom_details,omitempty"`
+}
+
+// Init prepares PagerDuty configuration
+func (p *PagerDuty) Init(c *config.Config) error {
+	p.RoutingKey = c.Handler.PagerDuty.RoutingKey
+	p.Url = c.Handler.PagerDuty.Url
+	p.Cluster = c.Cluster
+
+	if p.RoutingKey == "" {
+		p.RoutingKey = os.Getenv("KW_PAGERDUTY_ROUTING_KEY")
+	}
+	if p.Url == "" {
+		p.Url = defaultURL
+	}
+	p.client = &http.Client{Timeout: 10 * time.Second}
+
+	if p.RoutingKey == "" {
+		return fmt.Errorf(pagerDutyErrMsg, "Missing PagerDuty routing key")
+	}
+	return nil
+}
+
+// Handle handles an event.
+func (p *PagerDuty) Handle(e event.StatemonitorEvent) {
+	pdEvent := p.prepareEvent(e)
+
+	if err := p.postEvent(pdEvent); err != nil {
+		logrus.Errorf("PagerDuty: error sending %s of %s: %v", pdEvent.EventAction, pdEvent.DedupKey, err)
+		return
+	}
+
+	logrus.Printf("PagerDuty %s of %s successfully sent at %s", pdEvent.EventAction, pdEvent.DedupKey, time.Now())
+}
+
+func (p *PagerDuty) prepareEvent(e event.StatemonitorEvent) *Event {
+	_, recovered := e.Condition()
+	pdEvent := &Event{
+		RoutingKey:  p.RoutingKey,
+		EventAction: actionTrigger,
+		DedupKey:    e.IncidentKey(p.Cluster),
+	}
+	if recovered {
+		pdEvent.EventAction = actionResolve
+		return pdEvent
+	}
+
+	source := p.Cluster
+	if source == "" {
+		source = "kubestatewatch"
+	}
+	pdEvent.Payload = &Payload{
+		Summary:       summary(e),
+		Source:        source,
+		Severity:      event.SeverityFromStatus(e.Status),
+		Timestamp:     time.Now().UTC().Format(time.RFC3339),
+		Component:     e.Kind,
+		Group:         e.Namespace,
+		Class:         e.Reason,
+		CustomDetails: customDetails(e),
+	}
+	return pdEvent
+}
+
+// summary describes the event in one line, e.g. "Deployment default/api Updated: rollout failed"
+func summary(e event.StatemonitorEvent) string {
+	name := e.Name
+	if e.Namespace != "" {
+		name = e.Namespace + "/" + e.Name
+	}
+	s := fmt.Sprintf("%s %s %s", e.Kind, name, e.Reason)
+	if e.Details != "" {
+		s += ": " + e.Details
+	}
+	return utils.Truncate(s, maxSummaryLength)
+}
+
+func customDetails(e event.StatemonitorEvent) map[string]interface{} {
+	details := map[string]interface{}{
+		"kind":       e.Kind,
+		"name":       e.Name,
+		"namespace":  e.Namespace,
+		"apiVersion": e.ApiVersion,
+		"reason":     e.Reason,
+	}
+	if e.Details != "" {
+		details["details"] = e.Details
+	}
+	if len(e.Children) > 0 {
+		details["children"] = e.Children
+	}
+	if e.Diff != "" {
+		// the diff is sent as JSON when it is a JSON patch, so its operations can be read in the alert
+		var diff interface{}
+		if err := json.Unmarshal([]byte(e.Diff), &diff); err == nil {
+			details["diff"] = diff
+		} else {
+			details["diff"] = e.Diff
+		}
+	}
+	return details
+}
+
+func (p *PagerDuty) postEvent(pdEvent *Event) error {
+	message, err := json.Marshal(pdEvent)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.Url, bytes.NewBuffer(message))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, body)
+	}
+	return nil
+}
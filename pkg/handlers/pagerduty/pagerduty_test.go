@@ -0,0 +1,116 @@
+package pagerduty
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPagerDutyInit(t *testing.T) {
+	p := &PagerDuty{}
+
+	c := &config.Config{}
+	assert.Equal(t, fmt.Errorf(pagerDutyErrMsg, "Missing PagerDuty routing key"), p.Init(c))
+
+	c.Handler.PagerDuty.RoutingKey = "key"
+	assert.NoError(t, p.Init(c))
+	assert.Equal(t, defaultURL, p.Url)
+
+	t.Setenv("KW_PAGERDUTY_ROUTING_KEY", "env-key")
+	assert.NoError(t, p.Init(&config.Config{}))
+	assert.Equal(t, "env-key", p.RoutingKey)
+}
+
+// newTestServer returns a fake Events API recording the received events
+func newTestServer(t *testing.T, status int) (*httptest.Server, *[]Event) {
+	var received []Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e Event
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&e))
+		received = append(received, e)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, &received
+}
+
+func newTestHandler(t *testing.T, url string) *PagerDuty {
+	p := &PagerDuty{}
+	c := &config.Config{Cluster: "prod"}
+	c.Handler.PagerDuty = config.PagerDuty{RoutingKey: "key", Url: url}
+	assert.NoError(t, p.Init(c))
+	return p
+}
+
+func TestPagerDutyTrigger(t *testing.T) {
+	server, received := newTestServer(t, http.StatusAccepted)
+	p := newTestHandler(t, server.URL)
+
+	p.Handle(event.StatemonitorEvent{
+		Kind:       "Deployment",
+		Name:       "api",
+		Namespace:  "default",
+		ApiVersion: "apps/v1",
+		Reason:     "Deleted",
+		Status:     "Danger",
+		Diff:       `[{"op":"replace","path":"/spec/replicas","value":3}]`,
+	})
+
+	assert.Len(t, *received, 1)
+	e := (*received)[0]
+	assert.Equal(t, "key", e.RoutingKey)
+	assert.Equal(t, actionTrigger, e.EventAction)
+	assert.Equal(t, "prod/Deployment/default/api", e.DedupKey)
+	assert.Equal(t, "Deployment default/api Deleted", e.Payload.Summary)
+	assert.Equal(t, "prod", e.Payload.Source)
+	assert.Equal(t, event.SeverityCritical, e.Payload.Severity)
+	assert.Equal(t, "default", e.Payload.Group)
+	assert.Equal(t, []interface{}{map[string]interface{}{"op": "replace", "path": "/spec/replicas", "value": float64(3)}}, e.Payload.CustomDetails["diff"])
+}
+
+func TestPagerDutyResolve(t *testing.T) {
+	server, received := newTestServer(t, http.StatusAccepted)
+	p := newTestHandler(t, server.URL)
+
+	p.Handle(event.StatemonitorEvent{Kind: "Node", Name: "node-1", Reason: "NodeNotReady", Status: "Danger", Details: "Ready is False"})
+	p.Handle(event.StatemonitorEvent{Kind: "Node", Name: "node-1", Reason: "NodeMemoryPressure", Status: "Warning"})
+	p.Handle(event.StatemonitorEvent{Kind: "Node", Name: "node-1", Reason: "NodeMemoryPressureResolved", Status: "Normal"})
+	p.Handle(event.StatemonitorEvent{Kind: "Node", Name: "node-1", Reason: "NodeReady", Status: "Normal"})
+
+	assert.Len(t, *received, 4)
+	assert.Equal(t, Event{RoutingKey: "key", EventAction: actionTrigger, DedupKey: "prod/Node//node-1/NodeNotReady", Payload: (*received)[0].Payload}, (*received)[0])
+	assert.Equal(t, "Node node-1 NodeNotReady: Ready is False", (*received)[0].Payload.Summary)
+	assert.Equal(t, "prod/Node//node-1/NodeMemoryPressure", (*received)[1].DedupKey)
+	assert.Equal(t, Event{RoutingKey: "key", EventAction: actionResolve, DedupKey: "prod/Node//node-1/NodeMemoryPressure"}, (*received)[2])
+	assert.Equal(t, Event{RoutingKey: "key", EventAction: actionResolve, DedupKey: "prod/Node//node-1/NodeNotReady"}, (*received)[3])
+}
+
+func TestPagerDutyError(t *testing.T) {
+	server, _ := newTestServer(t, http.StatusBadRequest)
+	p := newTestHandler(t, server.URL)
+
+	err := p.postEvent(p.prepareEvent(event.StatemonitorEvent{Kind: "Pod", Name: "web", Namespace: "default", Reason: "Created"}))
+	assert.ErrorContains(t, err, "400")
+}
+
+func TestSummary(t *testing.T) {
+	assert.Equal(t, "Deployment default/api RolloutFailed: rollout failed: ProgressDeadlineExceeded",
+		summary(event.StatemonitorEvent{Kind: "Deployment", Name: "api", Namespace: "default", Reason: "RolloutFailed", Details: "rollout failed: ProgressDeadlineExceeded"}))
+
+	long := summary(event.StatemonitorEvent{Kind: "Pod", Name: "web", Reason: "CrashLoopBackOff", Details: strings.Repeat("x", 2000)})
+	assert.Len(t, long, maxSummaryLength)
+
+	// the multi-byte characters aren't split
+	long = summary(event.StatemonitorEvent{Kind: "ConfigMap", Name: "messages", Reason: "Updated", Details: strings.Repeat("日本", 1000)})
+	assert.True(t, utf8.ValidString(long))
+	assert.Equal(t, maxSummaryLength, utf8.RuneCountInString(long))
+	assert.Regexp(t, `[日本]\.\.\.$`, long)
+}
@@ -0,0 +1,321 @@
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/marvasgit/kubestatewatch/pkg/utils"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+	"github.com/sirupsen/logrus"
+)
+
+var s3ErrMsg = `
+%s
+
+You need to set the bucket
+using "bucket" in the s3 configuration or using environment variables:
+
+export KW_S3_BUCKET=audit
+
+The credentials are read from "accessKey" and "secretKey", KW_S3_ACCESS_KEY and KW_S3_SECRET_KEY,
+the AWS environment variables or the IAM role of the pod.
+
+`
+
+const (
+	defaultEndpoint     = "s3.amazonaws.com"
+	defaultRegion       = "us-east-1"
+	defaultBatchBytes   = 5 << 20
+	defaultFlushSeconds = 300
+	defaultRetries      = 3
+
+	// partitionFormat partitions the objects by date and hour
+	partitionFormat = "2006-01-02/15"
+	// objectTimeFormat is the time of the first event in the name of the objects
+	objectTimeFormat = "20060102T150405.000000000Z"
+)
+
+// S3 handler implements handler.Handler, handler.Flusher and handler.Stopper interfaces,
+// Buffers the events and uploads them as gzipped JSON lines objects to S3 compatible storage,
+// e.g. audit/prod/2024-01-01/12/20240101T120000.000000000Z-kubestatewatch-7d9c5.jsonl.gz
+type S3 struct {
+	Endpoint       string
+	Region         string
+	Bucket         string
+	Prefix         string
+	Cluster        string
+	PathStyle      bool
+	ObjectLockMode minio.RetentionMode
+	Retention      time.Duration
+	BatchBytes     int
+	FlushInterval  time.Duration
+	Retries        int
+
+	client *minio.Client
+	sse    encrypt.ServerSide
+	// instance distinguishes the objects of the replicas, the name of the pod
+	instance string
+	now      func() time.Time
+	batcher  *utils.Batcher[*entry]
+}
+
+// entry is a buffered event
+type entry struct {
+	timestamp time.Time
+	line      []byte
+}
+
+// Init prepares S3 configuration and starts uploading the buffered events
+func (s *S3) Init(c *config.Config) error {
+	conf := c.Handler.S3
+	s.Endpoint = conf.Endpoint
+	s.Region = conf.Region
+	s.Bucket = conf.Bucket
+	s.Prefix = strings.Trim(conf.Prefix, "/")
+	s.Cluster = c.Cluster
+	s.PathStyle = conf.PathStyle
+	s.ObjectLockMode = minio.RetentionMode(strings.ToUpper(conf.ObjectLockMode))
+	s.Retention = time.Duration(conf.RetentionDays) * 24 * time.Hour
+	s.BatchBytes = conf.BatchBytes
+	s.FlushInterval = time.Duration(conf.FlushSeconds) * time.Second
+	s.Retries = conf.Retries
+	accessKey := conf.AccessKey
+	secretKey := conf.SecretKey
+
+	if s.Bucket == "" {
+		s.Bucket = os.Getenv("KW_S3_BUCKET")
+	}
+	if accessKey == "" {
+		accessKey = os.Getenv("KW_S3_ACCESS_KEY")
+	}
+	if secretKey == "" {
+		secretKey = os.Getenv("KW_S3_SECRET_KEY")
+	}
+	if s.Endpoint == "" {
+		s.Endpoint = defaultEndpoint
+	}
+	if s.Region == "" {
+		s.Region = defaultRegion
+	}
+	if s.BatchBytes <= 0 {
+		s.BatchBytes = defaultBatchBytes
+	}
+	if s.FlushInterval <= 0 {
+		s.FlushInterval = defaultFlushSeconds * time.Second
+	}
+	if s.Retries <= 0 {
+		s.Retries = defaultRetries
+	}
+	if s.Bucket == "" {
+		return fmt.Errorf(s3ErrMsg, "Missing bucket")
+	}
+
+	switch s.ObjectLockMode {
+	case "":
+	case minio.Governance, minio.Compliance:
+		if s.Retention <= 0 {
+			return fmt.Errorf("missing retentionDays of the %s object lock mode", s.ObjectLockMode)
+		}
+	default:
+		return fmt.Errorf("invalid object lock mode %q, expected GOVERNANCE or COMPLIANCE", conf.ObjectLockMode)
+	}
+
+	var err error
+	switch conf.Encryption {
+	case "":
+	case "AES256":
+		s.sse = encrypt.NewSSE()
+	case "aws:kms":
+		if s.sse, err = encrypt.NewSSEKMS(conf.KmsKeyId, nil); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid encryption %q, expected AES256 or aws:kms", conf.Encryption)
+	}
+
+	host, secure, err := parseEndpoint(s.Endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint %q: %v", s.Endpoint, err)
+	}
+	tlsConfig, err := utils.TLSConfig(conf.TLS)
+	if err != nil {
+		return fmt.Errorf("invalid TLS configuration: %v", err)
+	}
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment, TLSClientConfig: tlsConfig}
+
+	creds := credentials.NewStaticV4(accessKey, secretKey, "")
+	if accessKey == "" {
+		creds = credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.IAM{Client: &http.Client{Transport: http.DefaultTransport}},
+		})
+	}
+	lookup := minio.BucketLookupAuto
+	if s.PathStyle {
+		lookup = minio.BucketLookupPath
+	}
+	s.client, err = minio.New(host, &minio.Options{
+		Creds:        creds,
+		Secure:       secure,
+		Region:       s.Region,
+		BucketLookup: lookup,
+		Transport:    transport,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.instance, err = os.Hostname()
+	if err != nil || s.instance == "" {
+		s.instance = "kubestatewatch"
+	}
+	s.now = time.Now
+	s.batcher = &utils.Batcher[*entry]{
+		Name:      "S3",
+		BatchSize: s.BatchBytes,
+		Interval:  s.FlushInterval,
+		Retries:   s.Retries,
+		Size:      func(e *entry) int { return len(e.line) },
+		Send:      s.send,
+	}
+	s.batcher.Start()
+	return nil
+}
+
+// Handle handles an event.
+func (s *S3) Handle(e event.StatemonitorEvent) {
+	now := s.now()
+	line, err := json.Marshal(event.NewRecord(e, s.Cluster, now))
+	if err != nil {
+		logrus.Errorf("S3: error encoding %s %s/%s: %v", e.Kind, e.Namespace, e.Name, err)
+		return
+	}
+	s.batcher.Add(&entry{timestamp: now, line: append(line, '\n')})
+}
+
+// Flush uploads the buffered events, in objects of at most BatchBytes per hour. The events of an upload failing
+// with a 429 or 5xx status stay buffered to be retried by the next flush, the others are dropped
+func (s *S3) Flush() {
+	s.batcher.Flush()
+}
+
+// Stop stops uploading the buffered events periodically and uploads them
+func (s *S3) Stop() {
+	s.batcher.Stop()
+}
+
+// send uploads the entries, one object per hour, returning the entries of the uploads that can be sent again
+func (s *S3) send(entries []*entry) ([]*entry, error) {
+	var failed []*entry
+	var errs []error
+	for _, partition := range partitions(entries) {
+		key := s.objectKey(partition)
+		err := s.upload(key, partition)
+		if err == nil {
+			logrus.Printf("S3: %d events successfully uploaded to %s at %s", len(partition), key, time.Now())
+			continue
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		if utils.IsRetryable(err) {
+			failed = append(failed, partition...)
+		}
+	}
+	return failed, errors.Join(errs...)
+}
+
+// partitions splits the entries by hour, keeping their order
+func partitions(entries []*entry) [][]*entry {
+	var partitions [][]*entry
+	index := map[string]int{}
+	for _, entry := range entries {
+		hour := entry.timestamp.UTC().Format(partitionFormat)
+		i, ok := index[hour]
+		if !ok {
+			i = len(partitions)
+			index[hour] = i
+			partitions = append(partitions, nil)
+		}
+		partitions[i] = append(partitions[i], entry)
+	}
+	return partitions
+}
+
+// objectKey names the object after the time of its first event, the retries of an upload overwriting the same object
+func (s *S3) objectKey(entries []*entry) string {
+	first := entries[0].timestamp.UTC()
+	cluster := s.Cluster
+	if cluster == "" {
+		cluster = "default"
+	}
+	name := fmt.Sprintf("%s-%s.jsonl.gz", first.Format(objectTimeFormat), s.instance)
+	return path.Join(s.Prefix, cluster, first.Format(partitionFormat), name)
+}
+
+// upload puts the gzipped lines of the entries
+func (s *S3) upload(key string, entries []*entry) error {
+	var body bytes.Buffer
+	gz := gzip.NewWriter(&body)
+	for _, entry := range entries {
+		if _, err := gz.Write(entry.line); err != nil {
+			return err
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	opts := minio.PutObjectOptions{
+		ContentType:          "application/gzip",
+		ServerSideEncryption: s.sse,
+	}
+	if s.ObjectLockMode != "" {
+		opts.Mode = s.ObjectLockMode
+		opts.RetainUntilDate = s.now().Add(s.Retention).UTC()
+		// the uploads of objects locked by a retention need their checksum
+		opts.SendContentMd5 = true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	_, err := s.client.PutObject(ctx, s.Bucket, key, &body, int64(body.Len()), opts)
+	if err != nil {
+		status := minio.ToErrorResponse(err).StatusCode
+		if status == 0 || utils.RetryableStatus(status) {
+			return utils.RetryableError{Err: err}
+		}
+		return err
+	}
+	return nil
+}
+
+// parseEndpoint returns the host of the endpoint and whether it uses https, the default without scheme
+func parseEndpoint(endpoint string) (string, bool, error) {
+	if !strings.Contains(endpoint, "://") {
+		return strings.TrimSuffix(endpoint, "/"), true, nil
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", false, err
+	}
+	switch u.Scheme {
+	case "http":
+		return u.Host, false, nil
+	case "https":
+		return u.Host, true, nil
+	}
+	return "", false, fmt.Errorf("unsupported scheme %s", u.Scheme)
+}
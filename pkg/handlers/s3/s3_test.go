@@ -0,0 +1,225 @@
+package s3
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/marvasgit/kubestatewatch/pkg/utils"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	// the retries of the client would slow down the tests, the handler retrying on the next flush
+	minio.MaxRetry = 1
+}
+
+func TestS3Init(t *testing.T) {
+	s := &S3{}
+
+	c := &config.Config{}
+	assert.Equal(t, fmt.Errorf(s3ErrMsg, "Missing bucket"), s.Init(c))
+
+	c.Handler.S3 = config.S3{Bucket: "audit", ObjectLockMode: "compliance"}
+	assert.Error(t, s.Init(c))
+
+	c.Handler.S3 = config.S3{Bucket: "audit", ObjectLockMode: "legal", RetentionDays: 365}
+	assert.Error(t, s.Init(c))
+
+	c.Handler.S3 = config.S3{Bucket: "audit", Encryption: "SSE-C"}
+	assert.Error(t, s.Init(c))
+
+	c.Handler.S3 = config.S3{Bucket: "audit", Endpoint: "ftp://minio:9000"}
+	assert.Error(t, s.Init(c))
+
+	c.Handler.S3 = config.S3{Bucket: "audit", Prefix: "/audit/", ObjectLockMode: "compliance", RetentionDays: 365, AccessKey: "key", SecretKey: "secret"}
+	assert.NoError(t, s.Init(c))
+	assert.Equal(t, "s3.amazonaws.com", s.Endpoint)
+	assert.Equal(t, "us-east-1", s.Region)
+	assert.Equal(t, "audit", s.Prefix)
+	assert.Equal(t, minio.Compliance, s.ObjectLockMode)
+	assert.Equal(t, 365*24*time.Hour, s.Retention)
+	assert.Equal(t, 5<<20, s.BatchBytes)
+	assert.Equal(t, 300*time.Second, s.FlushInterval)
+}
+
+func TestParseEndpoint(t *testing.T) {
+	for endpoint, expected := range map[string]struct {
+		host   string
+		secure bool
+	}{
+		"s3.eu-west-1.amazonaws.com": {"s3.eu-west-1.amazonaws.com", true},
+		"http://minio.minio:9000":    {"minio.minio:9000", false},
+		"https://minio.minio:9000/":  {"minio.minio:9000", true},
+	} {
+		host, secure, err := parseEndpoint(endpoint)
+		assert.NoError(t, err)
+		assert.Equal(t, expected.host, host, endpoint)
+		assert.Equal(t, expected.secure, secure, endpoint)
+	}
+}
+
+// upload is an object uploaded to the fakeStorage
+type upload struct {
+	path    string
+	header  http.Header
+	records []event.Record
+}
+
+// fakeStorage records the uploaded objects, answering them with status
+type fakeStorage struct {
+	mu      sync.Mutex
+	status  int
+	uploads []upload
+}
+
+func (f *fakeStorage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := decodeBody(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	gz, err := gzip.NewReader(body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	upload := upload{path: r.URL.Path, header: r.Header}
+	scanner := bufio.NewScanner(gz)
+	for scanner.Scan() {
+		var record event.Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		upload.records = append(upload.records, record)
+	}
+	f.uploads = append(f.uploads, upload)
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+		fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>SlowDown</Code><Message>Please reduce your request rate.</Message></Error>`)
+		return
+	}
+	w.Header().Set("ETag", `"d41d8cd98f00b204e9800998ecf8427e"`)
+}
+
+// decodeBody returns the payload of the request, decoding the aws-chunked body signed as a stream
+func decodeBody(r *http.Request) (io.Reader, error) {
+	if r.Header.Get("X-Amz-Content-Sha256") != "STREAMING-AWS4-HMAC-SHA256-PAYLOAD" {
+		return r.Body, nil
+	}
+	var payload bytes.Buffer
+	reader := bufio.NewReader(r.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		n, err := strconv.ParseInt(size, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return &payload, nil
+		}
+		if _, err := io.CopyN(&payload, reader, n+2); err != nil {
+			return nil, err
+		}
+		payload.Truncate(payload.Len() - 2)
+	}
+}
+
+func newTestHandler(t *testing.T, f *fakeStorage, conf config.S3) (*S3, *time.Time) {
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	s := &S3{}
+	c := &config.Config{Cluster: "prod"}
+	conf.Endpoint = server.URL
+	conf.Bucket = "audit"
+	conf.AccessKey = "minioadmin"
+	conf.SecretKey = "minioadmin"
+	conf.PathStyle = true
+	conf.FlushSeconds = 3600
+	c.Handler.S3 = conf
+	require.NoError(t, s.Init(c))
+	t.Cleanup(s.Stop)
+	s.instance = "kubestatewatch-0"
+
+	now := time.Date(2024, 1, 1, 12, 59, 59, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func TestUpload(t *testing.T) {
+	f := &fakeStorage{}
+	s, now := newTestHandler(t, f, config.S3{Prefix: "kubestatewatch", Encryption: "aws:kms", KmsKeyId: "alias/audit", ObjectLockMode: "COMPLIANCE", RetentionDays: 365})
+
+	s.Handle(event.StatemonitorEvent{Kind: "RoleBinding", Name: "admins", Namespace: "shop", Reason: "Created", Actor: "kubectl-create"})
+	*now = now.Add(time.Second)
+	s.Handle(event.StatemonitorEvent{Kind: "Secret", Name: "db", Namespace: "shop", Reason: "Updated", Diff: `[{"op":"replace","path":"/data/password"}]`})
+	s.Flush()
+
+	// partitioned by hour
+	require.Len(t, f.uploads, 2)
+	assert.Equal(t, "/audit/kubestatewatch/prod/2024-01-01/12/20240101T125959.000000000Z-kubestatewatch-0.jsonl.gz", f.uploads[0].path)
+	assert.Equal(t, "/audit/kubestatewatch/prod/2024-01-01/13/20240101T130000.000000000Z-kubestatewatch-0.jsonl.gz", f.uploads[1].path)
+
+	header := f.uploads[0].header
+	assert.Equal(t, "aws:kms", header.Get("X-Amz-Server-Side-Encryption"))
+	assert.Equal(t, "alias/audit", header.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"))
+	assert.Equal(t, "COMPLIANCE", header.Get("X-Amz-Object-Lock-Mode"))
+	assert.Equal(t, "2024-12-31T13:00:00Z", header.Get("X-Amz-Object-Lock-Retain-Until-Date"))
+	assert.NotEmpty(t, header.Get("Content-Md5"))
+	assert.Regexp(t, `Credential=minioadmin/\d{8}/us-east-1/s3/aws4_request`, header.Get("Authorization"))
+
+	require.Len(t, f.uploads[0].records, 1)
+	assert.Equal(t, "kubectl-create", f.uploads[0].records[0].Actor)
+	assert.Equal(t, "prod", f.uploads[0].records[0].Cluster)
+	assert.JSONEq(t, `[{"op":"replace","path":"/data/password"}]`, string(f.uploads[1].records[0].Diff))
+}
+
+func TestUploadErrors(t *testing.T) {
+	f := &fakeStorage{status: http.StatusServiceUnavailable}
+	s, now := newTestHandler(t, f, config.S3{Encryption: "AES256"})
+	entries := []*entry{{timestamp: *now, line: []byte(`{"kind":"ClusterRole","name":"admin","reason":"Updated"}` + "\n")}}
+
+	// the retries overwrite the same object
+	failed, err := s.send(entries)
+	assert.True(t, utils.IsRetryable(err))
+	assert.Equal(t, entries, failed)
+	_, err = s.send(entries)
+	assert.True(t, utils.IsRetryable(err))
+	require.Len(t, f.uploads, 2)
+	assert.Equal(t, f.uploads[0].path, f.uploads[1].path)
+	assert.Equal(t, "AES256", f.uploads[0].header.Get("X-Amz-Server-Side-Encryption"))
+
+	// the uploads denied aren't retried
+	f.status = http.StatusForbidden
+	failed, err = s.send(entries)
+	assert.Error(t, err)
+	assert.False(t, utils.IsRetryable(err))
+	assert.Empty(t, failed)
+}
@@ -0,0 +1,190 @@
+package splunk
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/marvasgit/kubestatewatch/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+var splunkErrMsg = `
+%s
+
+You need to set the HTTP Event Collector url and token
+using "url" and "token" in the splunk configuration or using environment variables:
+
+export KW_SPLUNK_URL=https://splunk:8088
+export KW_SPLUNK_TOKEN=hec_token
+
+`
+
+const (
+	defaultSourcetype   = "_json"
+	defaultSource       = "kubestatewatch"
+	defaultBatchSize    = 100
+	defaultFlushSeconds = 5
+	defaultRetries      = 3
+)
+
+// Splunk handler implements handler.Handler, handler.Flusher and handler.Stopper interfaces,
+// Buffers the events and sends them in batches to the HTTP Event Collector
+type Splunk struct {
+	Url           string
+	Index         string
+	Sourcetype    string
+	Source        string
+	Cluster       string
+	BatchSize     int
+	FlushInterval time.Duration
+	Retries       int
+
+	token   string
+	client  *http.Client
+	now     func() time.Time
+	batcher *utils.Batcher[*hecEvent]
+}
+
+// hecEvent is an event of the HTTP Event Collector
+type hecEvent struct {
+	// Time in seconds since the Unix epoch
+	Time       float64      `json:"time"`
+	Host       string       `json:"host,omitempty"`
+	Source     string       `json:"source,omitempty"`
+	Sourcetype string       `json:"sourcetype,omitempty"`
+	Index      string       `json:"index,omitempty"`
+	Event      event.Record `json:"event"`
+}
+
+// Init prepares Splunk configuration and starts sending the buffered events
+func (s *Splunk) Init(c *config.Config) error {
+	conf := c.Handler.Splunk
+	s.Url = strings.TrimSuffix(conf.Url, "/")
+	s.Index = conf.Index
+	s.Sourcetype = conf.Sourcetype
+	s.Source = conf.Source
+	s.Cluster = c.Cluster
+	s.BatchSize = conf.BatchSize
+	s.FlushInterval = time.Duration(conf.FlushSeconds) * time.Second
+	s.Retries = conf.Retries
+	s.token = conf.Token
+
+	if s.Url == "" {
+		s.Url = strings.TrimSuffix(os.Getenv("KW_SPLUNK_URL"), "/")
+	}
+	if s.token == "" {
+		s.token = os.Getenv("KW_SPLUNK_TOKEN")
+	}
+	if s.Sourcetype == "" {
+		s.Sourcetype = defaultSourcetype
+	}
+	if s.Source == "" {
+		s.Source = defaultSource
+	}
+	if s.BatchSize <= 0 {
+		s.BatchSize = defaultBatchSize
+	}
+	if s.FlushInterval <= 0 {
+		s.FlushInterval = defaultFlushSeconds * time.Second
+	}
+	if s.Retries <= 0 {
+		s.Retries = defaultRetries
+	}
+	if s.Url == "" || s.token == "" {
+		return fmt.Errorf(splunkErrMsg, "Missing HTTP Event Collector url or token")
+	}
+
+	tlsConfig, err := utils.TLSConfig(conf.TLS)
+	if err != nil {
+		return fmt.Errorf("invalid TLS configuration: %v", err)
+	}
+	s.client = &http.Client{Timeout: 10 * time.Second, Transport: &http.Transport{Proxy: http.ProxyFromEnvironment, TLSClientConfig: tlsConfig}}
+	s.now = time.Now
+	s.batcher = &utils.Batcher[*hecEvent]{
+		Name:      "Splunk",
+		BatchSize: s.BatchSize,
+		Interval:  s.FlushInterval,
+		Retries:   s.Retries,
+		Send:      s.sendBatch,
+	}
+	s.batcher.Start()
+	return nil
+}
+
+// Handle handles an event.
+func (s *Splunk) Handle(e event.StatemonitorEvent) {
+	now := s.now()
+	s.batcher.Add(&hecEvent{
+		Time:       float64(now.UnixMicro()) / 1e6,
+		Host:       s.Cluster,
+		Source:     s.Source,
+		Sourcetype: s.Sourcetype,
+		Index:      s.Index,
+		Event:      event.NewRecord(e, s.Cluster, now),
+	})
+}
+
+// Flush sends the buffered events, in requests of BatchSize. The events of a request failing with a 429 or 5xx status
+// stay buffered to be retried by the next flush, the others are dropped
+func (s *Splunk) Flush() {
+	s.batcher.Flush()
+}
+
+// Stop stops sending the buffered events periodically and sends them
+func (s *Splunk) Stop() {
+	s.batcher.Stop()
+}
+
+// sendBatch sends the events, returning them when they can be sent again
+func (s *Splunk) sendBatch(events []*hecEvent) ([]*hecEvent, error) {
+	err := s.send(events)
+	if utils.IsRetryable(err) {
+		return events, err
+	} else if err != nil {
+		return nil, err
+	}
+	logrus.Printf("Splunk: %d events successfully sent at %s", len(events), time.Now())
+	return nil, nil
+}
+
+// send posts the events, concatenated in one request as the HTTP Event Collector expects batches
+func (s *Splunk) send(events []*hecEvent) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, e := range events {
+		if err := encoder.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.Url+"/services/collector/event", &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Authorization", "Splunk "+s.token)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return utils.RetryableError{Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		err := fmt.Errorf("unexpected status %s: %s", resp.Status, message)
+		if utils.RetryableStatus(resp.StatusCode) {
+			return utils.RetryableError{Err: err}
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package splunk
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/marvasgit/kubestatewatch/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplunkInit(t *testing.T) {
+	s := &Splunk{}
+
+	c := &config.Config{}
+	assert.Equal(t, fmt.Errorf(splunkErrMsg, "Missing HTTP Event Collector url or token"), s.Init(c))
+
+	c.Handler.Splunk = config.Splunk{Url: "https://splunk:8088/"}
+	assert.Error(t, s.Init(c))
+
+	c.Handler.Splunk = config.Splunk{Url: "https://splunk:8088/", Token: "token"}
+	assert.NoError(t, s.Init(c))
+	assert.Equal(t, "https://splunk:8088", s.Url)
+	assert.Equal(t, "_json", s.Sourcetype)
+	assert.Equal(t, 100, s.BatchSize)
+	assert.Equal(t, 5*time.Second, s.FlushInterval)
+}
+
+// fakeCollector decodes the batches of events, answering them with status
+type fakeCollector struct {
+	mu      sync.Mutex
+	status  int
+	batches [][]hecEvent
+}
+
+func (f *fakeCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.URL.Path != "/services/collector/event" || r.Header.Get("Authorization") != "Splunk secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var batch []hecEvent
+	decoder := json.NewDecoder(r.Body)
+	for {
+		var e hecEvent
+		if err := decoder.Decode(&e); err == io.EOF {
+			break
+		} else if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		batch = append(batch, e)
+	}
+	f.batches = append(f.batches, batch)
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+		return
+	}
+	fmt.Fprint(w, `{"text":"Success","code":0}`)
+}
+
+func newTestHandler(t *testing.T, f *fakeCollector) *Splunk {
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	s := &Splunk{}
+	c := &config.Config{Cluster: "prod"}
+	c.Handler.Splunk = config.Splunk{Url: server.URL, Token: "secret", Index: "k8s_audit", Sourcetype: "kubestatewatch", FlushSeconds: 3600}
+	require.NoError(t, s.Init(c))
+	t.Cleanup(s.Stop)
+	s.now = func() time.Time { return time.Date(2024, 1, 1, 12, 0, 0, 500000000, time.UTC) }
+	return s
+}
+
+func TestSend(t *testing.T) {
+	f := &fakeCollector{}
+	s := newTestHandler(t, f)
+
+	s.Handle(event.StatemonitorEvent{Kind: "RoleBinding", Name: "admins", Namespace: "shop", Reason: "Created", Actor: "kubectl-create"})
+	s.Handle(event.StatemonitorEvent{Kind: "Secret", Name: "db", Namespace: "shop", Reason: "Updated", Status: "Warning", Diff: `[{"op":"replace","path":"/data/password"}]`})
+	s.Flush()
+
+	require.Len(t, f.batches, 1)
+	require.Len(t, f.batches[0], 2)
+	e := f.batches[0][0]
+	assert.Equal(t, 1704110400.5, e.Time)
+	assert.Equal(t, "prod", e.Host)
+	assert.Equal(t, "kubestatewatch", e.Source)
+	assert.Equal(t, "kubestatewatch", e.Sourcetype)
+	assert.Equal(t, "k8s_audit", e.Index)
+	assert.Equal(t, "RoleBinding", e.Event.Kind)
+	assert.Equal(t, "kubectl-create", e.Event.Actor)
+	assert.JSONEq(t, `[{"op":"replace","path":"/data/password"}]`, string(f.batches[0][1].Event.Diff))
+}
+
+func TestSendErrors(t *testing.T) {
+	f := &fakeCollector{status: http.StatusServiceUnavailable}
+	s := newTestHandler(t, f)
+	events := []*hecEvent{{Time: 1704110400, Event: event.Record{Kind: "ClusterRole", Name: "admin", Reason: "Updated"}}}
+
+	failed, err := s.sendBatch(events)
+	assert.True(t, utils.IsRetryable(err))
+	assert.Equal(t, events, failed)
+
+	// the requests rejected as invalid aren't retried
+	f.status = http.StatusBadRequest
+	failed, err = s.sendBatch(events)
+	assert.Error(t, err)
+	assert.False(t, utils.IsRetryable(err))
+	assert.Empty(t, failed)
+}
@@ -0,0 +1,274 @@
+package syslog
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/marvasgit/kubestatewatch/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+var syslogErrMsg = `
+%s
+
+You need to set the syslog server address
+using "address" in the syslog configuration or using environment variables:
+
+export KW_SYSLOG_ADDRESS=syslog.logging:514
+
+`
+
+const (
+	defaultFacility = "local0"
+	defaultAppName  = "kubestatewatch"
+	// defaultStructuredDataId uses the enterprise number reserved for documentation by RFC 5612
+	defaultStructuredDataId = "event@32473"
+	// defaultMaxUdpBytes is the length of the messages every receiver should accept over UDP, see RFC 5426
+	defaultMaxUdpBytes = 2048
+	// truncatedMarker ends the truncated messages
+	truncatedMarker = "...(truncated)"
+
+	dialTimeout  = 10 * time.Second
+	writeTimeout = 10 * time.Second
+)
+
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// severities maps the severities of the events to the syslog severities crit, warning and info
+var severities = map[string]int{
+	event.SeverityCritical: 2,
+	event.SeverityWarning:  4,
+	event.SeverityInfo:     6,
+}
+
+// Syslog handler implements handler.Handler interface,
+// Sends the events as RFC 5424 messages over UDP, TCP or TLS, the fields of the event being a structured data element
+type Syslog struct {
+	Network          string
+	Address          string
+	Facility         int
+	Hostname         string
+	AppName          string
+	StructuredDataId string
+	Cluster          string
+	MaxUdpBytes      int
+
+	tlsConfig *tls.Config
+	now       func() time.Time
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// Init prepares syslog configuration, the connection being opened by the first message
+func (s *Syslog) Init(c *config.Config) error {
+	conf := c.Handler.Syslog
+	s.Network = strings.ToLower(conf.Network)
+	s.Address = conf.Address
+	s.Hostname = conf.Hostname
+	s.AppName = conf.AppName
+	s.StructuredDataId = conf.StructuredDataId
+	s.Cluster = c.Cluster
+	s.MaxUdpBytes = conf.MaxUdpBytes
+
+	if s.Address == "" {
+		s.Address = os.Getenv("KW_SYSLOG_ADDRESS")
+	}
+	if s.Network == "" {
+		s.Network = "udp"
+	}
+	if s.Hostname == "" {
+		s.Hostname = s.Cluster
+	}
+	if s.Hostname == "" {
+		s.Hostname, _ = os.Hostname()
+	}
+	if s.AppName == "" {
+		s.AppName = defaultAppName
+	}
+	if s.StructuredDataId == "" {
+		s.StructuredDataId = defaultStructuredDataId
+	}
+	if s.MaxUdpBytes <= 0 {
+		s.MaxUdpBytes = defaultMaxUdpBytes
+	}
+	s.now = time.Now
+
+	if s.Address == "" {
+		return fmt.Errorf(syslogErrMsg, "Missing syslog address")
+	}
+	if s.Network != "udp" && s.Network != "tcp" && s.Network != "tls" {
+		return fmt.Errorf("invalid network %q, expected udp, tcp or tls", conf.Network)
+	}
+
+	facility := strings.ToLower(conf.Facility)
+	if facility == "" {
+		facility = defaultFacility
+	}
+	var ok bool
+	if s.Facility, ok = facilities[facility]; !ok {
+		return fmt.Errorf("invalid facility %q", conf.Facility)
+	}
+
+	if s.Network == "tls" {
+		tlsConf := conf.TLS
+		tlsConf.Enabled = true
+		var err error
+		if s.tlsConfig, err = utils.TLSConfig(tlsConf); err != nil {
+			return fmt.Errorf("invalid TLS configuration: %v", err)
+		}
+	}
+	return nil
+}
+
+// Handle handles an event.
+func (s *Syslog) Handle(e event.StatemonitorEvent) {
+	if err := s.send(s.format(e)); err != nil {
+		logrus.Errorf("Syslog: error sending %s %s/%s to %s: %v", e.Kind, e.Namespace, e.Name, s.Address, err)
+		return
+	}
+
+	logrus.Printf("Syslog message of %s %s/%s successfully sent at %s", e.Kind, e.Namespace, e.Name, time.Now())
+}
+
+// format returns the RFC 5424 message of the event:
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD-ID kind="..." ...] MSG
+func (s *Syslog) format(e event.StatemonitorEvent) string {
+	priority := s.Facility*8 + severities[event.SeverityFromStatus(e.Status)]
+
+	var sd strings.Builder
+	sd.WriteString("[" + s.StructuredDataId)
+	for _, param := range [][2]string{
+		{"kind", e.Kind},
+		{"apiVersion", e.ApiVersion},
+		{"namespace", e.Namespace},
+		{"name", e.Name},
+		{"reason", e.Reason},
+		{"severity", event.SeverityFromStatus(e.Status)},
+		{"cluster", s.Cluster},
+		{"actor", e.Actor},
+		{"route", e.Route},
+	} {
+		if param[1] != "" {
+			sd.WriteString(" " + param[0] + `="` + escapeParamValue(param[1]) + `"`)
+		}
+	}
+	sd.WriteString("]")
+
+	return fmt.Sprintf("<%d>1 %s %s %s %d %s %s %s",
+		priority,
+		s.now().UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		headerField(s.Hostname, 255),
+		headerField(s.AppName, 48),
+		os.Getpid(),
+		headerField(e.Reason, 32),
+		sd.String(),
+		message(e),
+	)
+}
+
+// message returns the summary of the event on one line, followed by its details and diff
+func message(e event.StatemonitorEvent) string {
+	name := e.Name
+	if e.Namespace != "" {
+		name = e.Namespace + "/" + e.Name
+	}
+	msg := fmt.Sprintf("%s %s %s", e.Kind, name, e.Reason)
+	if e.Details != "" {
+		msg += ": " + e.Details
+	}
+	if len(e.Children) > 0 {
+		msg += " children: " + strings.Join(e.Children, ", ")
+	}
+	if e.Diff != "" {
+		msg += " diff: " + e.Diff
+	}
+	return strings.NewReplacer("\r\n", " ", "\n", " ").Replace(msg)
+}
+
+// headerField returns the value as printable US-ASCII of at most max characters, "-" for an empty value
+func headerField(value string, max int) string {
+	if value == "" {
+		return "-"
+	}
+	field := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, value)
+	if len(field) > max {
+		field = field[:max]
+	}
+	return field
+}
+
+// escapeParamValue escapes the characters ", \ and ] of the values of the structured data parameters
+func escapeParamValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
+
+// truncate cuts the message to at most max bytes on a character boundary, ending it with truncatedMarker
+func truncate(msg string, max int) string {
+	if len(msg) <= max {
+		return msg
+	}
+	cut := max - len(truncatedMarker)
+	if cut < 0 {
+		cut = 0
+	}
+	for cut > 0 && !utf8.RuneStart(msg[cut]) {
+		cut--
+	}
+	return msg[:cut] + truncatedMarker
+}
+
+// send writes the message, framed by its length over TCP and TLS, reconnecting once after a failed write.
+// The UDP messages are truncated to MaxUdpBytes, the longer datagrams being rejected
+func (s *Syslog) send(msg string) error {
+	if s.Network == "udp" {
+		msg = truncate(msg, s.MaxUdpBytes)
+	} else {
+		msg = strconv.Itoa(len(msg)) + " " + msg
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if s.conn == nil {
+			if s.conn, err = s.dial(); err != nil {
+				return err
+			}
+		}
+		s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if _, err = s.conn.Write([]byte(msg)); err == nil {
+			return nil
+		}
+		s.conn.Close()
+		s.conn = nil
+	}
+	return err
+}
+
+func (s *Syslog) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	if s.Network == "tls" {
+		return tls.DialWithDialer(dialer, "tcp", s.Address, s.tlsConfig)
+	}
+	return dialer.Dial(s.Network, s.Address)
+}
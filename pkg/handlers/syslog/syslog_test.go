@@ -0,0 +1,171 @@
+package syslog
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/marvasgit/kubestatewatch/config"
+	"github.com/marvasgit/kubestatewatch/pkg/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyslogInit(t *testing.T) {
+	s := &Syslog{}
+
+	c := &config.Config{}
+	assert.Equal(t, fmt.Errorf(syslogErrMsg, "Missing syslog address"), s.Init(c))
+
+	c.Handler.Syslog = config.Syslog{Address: "syslog:514", Network: "sctp"}
+	assert.EqualError(t, s.Init(c), `invalid network "sctp", expected udp, tcp or tls`)
+
+	c.Handler.Syslog = config.Syslog{Address: "syslog:514", Facility: "local9"}
+	assert.EqualError(t, s.Init(c), `invalid facility "local9"`)
+
+	c.Cluster = "prod"
+	c.Handler.Syslog = config.Syslog{Address: "syslog:514"}
+	assert.NoError(t, s.Init(c))
+	assert.Equal(t, "udp", s.Network)
+	assert.Equal(t, 16, s.Facility)
+	assert.Equal(t, "prod", s.Hostname)
+}
+
+func TestFormat(t *testing.T) {
+	s := &Syslog{}
+	c := &config.Config{Cluster: "prod"}
+	c.Handler.Syslog = config.Syslog{Address: "syslog:514", Facility: "auth"}
+	require.NoError(t, s.Init(c))
+	s.now = func() time.Time { return time.Date(2024, 1, 1, 12, 0, 0, 123456789, time.UTC) }
+
+	e := event.StatemonitorEvent{Kind: "ClusterRoleBinding", ApiVersion: "rbac.authorization.k8s.io/v1", Name: "admin", Reason: "Updated", Status: "Danger",
+		Actor: "kubectl-edit", Diff: `[{"op":"add","path":"/subjects/1","value":{"kind":"User","name":"eve]"}}]`}
+	assert.Equal(t, "<34>1 2024-01-01T12:00:00.123456Z prod kubestatewatch "+strconv.Itoa(os.Getpid())+" Updated "+
+		`[event@32473 kind="ClusterRoleBinding" apiVersion="rbac.authorization.k8s.io/v1" name="admin" reason="Updated" severity="critical" cluster="prod" actor="kubectl-edit"] `+
+		`ClusterRoleBinding admin Updated diff: [{"op":"add","path":"/subjects/1","value":{"kind":"User","name":"eve]"}}]`, s.format(e))
+
+	s.Hostname = ""
+	assert.Contains(t, s.format(event.StatemonitorEvent{Kind: "Secret", Name: "db", Namespace: "shop", Reason: "Created", Details: "a\nb", Actor: `x"y`}),
+		`<38>1 2024-01-01T12:00:00.123456Z - kubestatewatch`)
+	assert.Contains(t, s.format(event.StatemonitorEvent{Kind: "Secret", Name: "db", Namespace: "shop", Reason: "Created", Details: "a\nb", Actor: `x"y`}),
+		`actor="x\"y"] Secret shop/db Created: a b`)
+}
+
+func newTestHandler(t *testing.T, conf config.Syslog) *Syslog {
+	s := &Syslog{}
+	c := &config.Config{Cluster: "prod"}
+	c.Handler.Syslog = conf
+	require.NoError(t, s.Init(c))
+	return s
+}
+
+var testEvent = event.StatemonitorEvent{Kind: "Secret", Name: "db", Namespace: "shop", Reason: "Deleted", Status: "Danger"}
+
+func TestUDP(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	s := newTestHandler(t, config.Syslog{Address: listener.LocalAddr().String()})
+	s.Handle(testEvent)
+
+	buf := make([]byte, 2048)
+	listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := listener.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.Equal(t, s.format(testEvent)[:4], string(buf[:4]))
+	assert.True(t, strings.HasSuffix(string(buf[:n]), "Secret shop/db Deleted"))
+}
+
+func TestUDPTruncate(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	// the diff wouldn't fit a datagram
+	s := newTestHandler(t, config.Syslog{Address: listener.LocalAddr().String()})
+	e := testEvent
+	e.Reason = "Updated"
+	e.Diff = `[{"op":"replace","path":"/data/config","value":"` + strings.Repeat("é", 70000) + `"}]`
+	s.Handle(e)
+
+	buf := make([]byte, 65536)
+	listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := listener.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.Equal(t, 2048, s.MaxUdpBytes)
+	assert.LessOrEqual(t, n, 2048)
+	msg := string(buf[:n])
+	assert.Equal(t, s.format(e)[:4], msg[:4])
+	assert.Contains(t, msg, "Secret shop/db Updated diff: ")
+	assert.True(t, strings.HasSuffix(msg, "...(truncated)"))
+	assert.True(t, utf8.ValidString(msg))
+
+	assert.Equal(t, "short", truncate("short", 16))
+	assert.Equal(t, "abc...(truncated)", truncate("abcdefghijklmnopqrstuvwxyz", 17))
+}
+
+// readFrames reads count messages framed by their length from the first connection to the listener
+func readFrames(t *testing.T, listener net.Listener, count int) []string {
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	reader := bufio.NewReader(conn)
+	var messages []string
+	for i := 0; i < count; i++ {
+		length, err := reader.ReadString(' ')
+		require.NoError(t, err)
+		n, err := strconv.Atoi(strings.TrimSpace(length))
+		require.NoError(t, err)
+		msg := make([]byte, n)
+		_, err = io.ReadFull(reader, msg)
+		require.NoError(t, err)
+		messages = append(messages, string(msg))
+	}
+	return messages
+}
+
+func TestTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	s := newTestHandler(t, config.Syslog{Network: "tcp", Address: listener.Addr().String()})
+	s.Handle(testEvent)
+	s.Handle(testEvent)
+
+	messages := readFrames(t, listener, 2)
+	assert.True(t, strings.HasPrefix(messages[0], "<130>1 "))
+	assert.True(t, strings.HasSuffix(messages[1], "Secret shop/db Deleted"))
+}
+
+func TestTLS(t *testing.T) {
+	// the certificate of httptest is valid for 127.0.0.1
+	server := httptest.NewTLSServer(nil)
+	server.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600))
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: server.TLS.Certificates})
+	require.NoError(t, err)
+	defer listener.Close()
+
+	s := newTestHandler(t, config.Syslog{Network: "tls", Address: listener.Addr().String(), TLS: config.TLS{CaFile: caFile}})
+	go s.Handle(testEvent)
+
+	messages := readFrames(t, listener, 1)
+	assert.True(t, strings.HasSuffix(messages[0], "Secret shop/db Deleted"))
+}
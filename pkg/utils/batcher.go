@@ -0,0 +1,199 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// defaultMaxBuffered bounds the events kept by default while the destination is unavailable
+const defaultMaxBuffered = 10000
+
+// RetryableError is the error of a request that can be sent again, e.g. failing with a 429 or 5xx status
+type RetryableError struct {
+	Err error
+}
+
+func (e RetryableError) Error() string { return e.Err.Error() }
+
+func (e RetryableError) Unwrap() error { return e.Err }
+
+// IsRetryable returns whether the request failing with err can be sent again
+func IsRetryable(err error) bool {
+	var retryable RetryableError
+	return errors.As(err, &retryable)
+}
+
+// RetryableStatus returns whether a request failing with the status can be sent again
+func RetryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// Batcher buffers the events of a handler and sends them in batches from a background goroutine,
+// every Interval and as soon as BatchSize is reached. The events returned by Send are sent again
+// by the next flushes, up to Retries times, the others are dropped
+type Batcher[T comparable] struct {
+	// Name of the handler in the logs
+	Name      string
+	BatchSize int
+	Interval  time.Duration
+	Retries   int
+	// MaxBuffered bounds the events kept while the destination is unavailable, the oldest being dropped, 10000 by default
+	MaxBuffered int
+	// Size weighs the events against BatchSize, e.g. their bytes, 1 per event when nil
+	Size func(T) int
+	// Send sends a batch of at most BatchSize, returning the events to send again
+	Send func(batch []T) ([]T, error)
+
+	// full signals a full batch to the flushing goroutine
+	full     chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+
+	mu     sync.Mutex
+	buffer []T
+	// bufferedSize is the size of the buffer
+	bufferedSize int
+	// flushMu serializes the batches
+	flushMu sync.Mutex
+	// attempts of the events sent again, only used while flushing
+	attempts map[T]int
+}
+
+// Start starts flushing the buffered events until Stop
+func (b *Batcher[T]) Start() {
+	if b.BatchSize <= 0 {
+		b.BatchSize = 1
+	}
+	if b.MaxBuffered <= 0 {
+		b.MaxBuffered = defaultMaxBuffered
+	}
+	b.full = make(chan struct{}, 1)
+	b.stop = make(chan struct{})
+	b.done = make(chan struct{})
+
+	go func() {
+		defer close(b.done)
+		ticker := time.NewTicker(b.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-b.full:
+			case <-b.stop:
+				return
+			}
+			b.Flush()
+		}
+	}()
+}
+
+// Stop stops the flushing goroutine and sends the buffered events
+func (b *Batcher[T]) Stop() {
+	b.stopOnce.Do(func() {
+		close(b.stop)
+		<-b.done
+	})
+	b.Flush()
+}
+
+// Add buffers an event, signaling the flushing goroutine once BatchSize is reached
+func (b *Batcher[T]) Add(item T) {
+	b.mu.Lock()
+	b.buffer = append(b.buffer, item)
+	b.bufferedSize += b.size(item)
+	full := b.bufferedSize >= b.BatchSize
+	b.mu.Unlock()
+
+	if full {
+		select {
+		case b.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Flush sends the buffered events in batches of BatchSize. When a batch fails entirely,
+// the following batches aren't sent, staying buffered for the next flush
+func (b *Batcher[T]) Flush() {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	b.mu.Lock()
+	items := b.buffer
+	b.buffer = nil
+	b.bufferedSize = 0
+	b.mu.Unlock()
+	if len(items) == 0 {
+		return
+	}
+
+	attempts := map[T]int{}
+	var retries []T
+	dropped := 0
+	for len(items) > 0 {
+		n, size := 0, 0
+		for n < len(items) && size < b.BatchSize {
+			size += b.size(items[n])
+			n++
+		}
+		batch := items[:n]
+		items = items[n:]
+
+		failed, err := b.Send(batch)
+		if err != nil {
+			logrus.Errorf("%s: error sending %d events: %v", b.Name, len(batch), err)
+		}
+		for _, item := range failed {
+			attempts[item] = b.attempts[item] + 1
+			if attempts[item] > b.Retries {
+				delete(attempts, item)
+				dropped++
+				continue
+			}
+			retries = append(retries, item)
+		}
+		if err != nil && len(failed) == len(batch) {
+			for _, item := range items {
+				if previous, ok := b.attempts[item]; ok {
+					attempts[item] = previous
+				}
+			}
+			retries = append(retries, items...)
+			break
+		}
+	}
+	b.attempts = attempts
+	if dropped > 0 {
+		logrus.Errorf("%s: dropping %d events after %d attempts", b.Name, dropped, b.Retries+1)
+	}
+	if len(retries) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buffer = append(retries, b.buffer...)
+	if dropped := len(b.buffer) - b.MaxBuffered; dropped > 0 {
+		logrus.Errorf("%s: dropping the %d oldest buffered events", b.Name, dropped)
+		for _, item := range b.buffer[:dropped] {
+			delete(b.attempts, item)
+		}
+		b.buffer = b.buffer[dropped:]
+	}
+	b.bufferedSize = 0
+	for _, item := range b.buffer {
+		b.bufferedSize += b.size(item)
+	}
+}
+
+func (b *Batcher[T]) size(item T) int {
+	if b.Size == nil {
+		return 1
+	}
+	return b.Size(item)
+}
@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDestination records the batches, failing the events listed in fail
+type fakeDestination struct {
+	mu      sync.Mutex
+	fail    map[*string]bool
+	down    bool
+	batches [][]string
+}
+
+func (f *fakeDestination) send(batch []*string) ([]*string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var values []string
+	var failed []*string
+	for _, item := range batch {
+		values = append(values, *item)
+		if f.down || f.fail[item] {
+			failed = append(failed, item)
+		}
+	}
+	f.batches = append(f.batches, values)
+	if f.down {
+		return failed, RetryableError{errors.New("unexpected status 503 Service Unavailable")}
+	}
+	return failed, nil
+}
+
+func (f *fakeDestination) received() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]string(nil), f.batches...)
+}
+
+func newTestBatcher(t *testing.T, f *fakeDestination) *Batcher[*string] {
+	b := &Batcher[*string]{Name: "Test", BatchSize: 2, Interval: time.Hour, Retries: 3, MaxBuffered: 4, Send: f.send}
+	b.Start()
+	t.Cleanup(b.Stop)
+	return b
+}
+
+func item(value string) *string {
+	return &value
+}
+
+func TestBatcherBatchSize(t *testing.T) {
+	f := &fakeDestination{}
+	b := newTestBatcher(t, f)
+
+	b.Add(item("a"))
+	assert.Never(t, func() bool { return len(f.received()) > 0 }, 50*time.Millisecond, 10*time.Millisecond)
+	b.Add(item("b"))
+	assert.Eventually(t, func() bool { return len(f.received()) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"a", "b"}, f.received()[0])
+}
+
+func TestBatcherInterval(t *testing.T) {
+	f := &fakeDestination{}
+	b := &Batcher[*string]{Name: "Test", BatchSize: 100, Interval: 20 * time.Millisecond, Send: f.send}
+	b.Start()
+	defer b.Stop()
+
+	b.Add(item("a"))
+	assert.Eventually(t, func() bool { return len(f.received()) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 10000, b.MaxBuffered)
+}
+
+func TestBatcherSize(t *testing.T) {
+	f := &fakeDestination{}
+	b := &Batcher[*string]{Name: "Test", BatchSize: 4, Interval: time.Hour, Size: func(s *string) int { return len(*s) }, Send: f.send}
+	b.Start()
+	defer b.Stop()
+
+	b.Add(item("abc"))
+	b.Add(item("d"))
+	assert.Eventually(t, func() bool { return len(f.received()) == 1 }, time.Second, 10*time.Millisecond)
+}
+
+func TestBatcherChunks(t *testing.T) {
+	f := &fakeDestination{}
+	b := &Batcher[*string]{Name: "Test", BatchSize: 2, Send: f.send}
+
+	// buffered while the destination was down
+	for _, value := range []string{"a", "b", "c", "d", "e"} {
+		b.buffer = append(b.buffer, item(value))
+	}
+	b.Flush()
+	assert.Equal(t, [][]string{{"a", "b"}, {"c", "d"}, {"e"}}, f.received())
+}
+
+func TestBatcherRetries(t *testing.T) {
+	f := &fakeDestination{fail: map[*string]bool{}}
+	b := newTestBatcher(t, f)
+
+	throttled := item("throttled")
+	f.fail[throttled] = true
+	b.Add(item("a"))
+	b.Add(throttled)
+	b.Flush()
+
+	// only the failed events are sent again, the first attempt and 3 retries
+	for i := 0; i < 5; i++ {
+		b.Flush()
+	}
+	assert.Equal(t, [][]string{{"a", "throttled"}, {"throttled"}, {"throttled"}, {"throttled"}}, f.received())
+	assert.Empty(t, b.buffer)
+	assert.Empty(t, b.attempts)
+}
+
+func TestBatcherDown(t *testing.T) {
+	f := &fakeDestination{down: true}
+	b := newTestBatcher(t, f)
+
+	for _, value := range []string{"a", "b", "c", "d", "e", "f"} {
+		b.mu.Lock()
+		b.buffer = append(b.buffer, item(value))
+		b.mu.Unlock()
+	}
+	b.Flush()
+
+	// the following batches aren't sent once a batch fails, the oldest events beyond MaxBuffered being dropped
+	assert.Equal(t, [][]string{{"a", "b"}}, f.received())
+	var buffered []string
+	for _, item := range b.buffer {
+		buffered = append(buffered, *item)
+	}
+	assert.Equal(t, []string{"c", "d", "e", "f"}, buffered)
+
+	f.mu.Lock()
+	f.down = false
+	f.mu.Unlock()
+	b.Stop()
+	assert.Equal(t, [][]string{{"a", "b"}, {"c", "d"}, {"e", "f"}}, f.received())
+}
+
+func TestRetryableStatus(t *testing.T) {
+	assert.True(t, RetryableStatus(http.StatusTooManyRequests))
+	assert.True(t, RetryableStatus(http.StatusBadGateway))
+	assert.False(t, RetryableStatus(http.StatusBadRequest))
+	assert.True(t, IsRetryable(RetryableError{errors.New("timeout")}))
+	assert.False(t, IsRetryable(errors.New("invalid")))
+}
@@ -4,16 +4,25 @@ import (
 	"os"
 
 	"github.com/sirupsen/logrus"
+	admissionregistration_v1 "k8s.io/api/admissionregistration/v1"
 	apps_v1 "k8s.io/api/apps/v1"
+	autoscaling_v1 "k8s.io/api/autoscaling/v1"
 	batch_v1 "k8s.io/api/batch/v1"
 	api_v1 "k8s.io/api/core/v1"
+	discovery_v1 "k8s.io/api/discovery/v1"
+	events_v1 "k8s.io/api/events/v1"
 	ext_v1beta1 "k8s.io/api/extensions/v1beta1"
 	networking_v1 "k8s.io/api/networking/v1"
+	policy_v1 "k8s.io/api/policy/v1"
 	rbac_v1 "k8s.io/api/rbac/v1"
-	events_v1 "k8s.io/api/events/v1"
 	rbac_v1beta1 "k8s.io/api/rbac/v1beta1"
+	scheduling_v1 "k8s.io/api/scheduling/v1"
+	storage_v1 "k8s.io/api/storage/v1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
@@ -56,6 +65,66 @@ func GetClientOutOfCluster() kubernetes.Interface {
 	return clientset
 }
 
+// GetMetadataClient returns a k8s metadata client to the request from inside of cluster
+func GetMetadataClient() metadata.Interface {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		logrus.Fatalf("Can not get kubernetes config: %v", err)
+	}
+
+	client, err := metadata.NewForConfig(config)
+	if err != nil {
+		logrus.Fatalf("Can not create kubernetes metadata client: %v", err)
+	}
+
+	return client
+}
+
+// GetMetadataClientOutOfCluster returns a k8s metadata client to the request from outside of cluster
+func GetMetadataClientOutOfCluster() metadata.Interface {
+	config, err := buildOutOfClusterConfig()
+	if err != nil {
+		logrus.Fatalf("Can not get kubernetes config: %v", err)
+	}
+
+	client, err := metadata.NewForConfig(config)
+	if err != nil {
+		logrus.Fatalf("Can not create kubernetes metadata client: %v", err)
+	}
+
+	return client
+}
+
+// GetDynamicClient returns a k8s dynamic client to the request from inside of cluster
+func GetDynamicClient() dynamic.Interface {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		logrus.Fatalf("Can not get kubernetes config: %v", err)
+	}
+
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		logrus.Fatalf("Can not create kubernetes dynamic client: %v", err)
+	}
+
+	return client
+}
+
+// GetDynamicClientOutOfCluster returns a k8s dynamic client to the request from outside of cluster
+func GetDynamicClientOutOfCluster() dynamic.Interface {
+	config, err := buildOutOfClusterConfig()
+	if err != nil {
+		logrus.Fatalf("Can not get kubernetes config: %v", err)
+	}
+
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		logrus.Fatalf("Can not create kubernetes dynamic client: %v", err)
+	}
+
+	return client
+}
+
 // GetObjectMetaData returns metadata of a given k8s object
 func GetObjectMetaData(obj interface{}) (objectMeta meta_v1.ObjectMeta) {
 
@@ -68,6 +137,8 @@ func GetObjectMetaData(obj interface{}) (objectMeta meta_v1.ObjectMeta) {
 		objectMeta = object.ObjectMeta
 	case *apps_v1.DaemonSet:
 		objectMeta = object.ObjectMeta
+	case *apps_v1.StatefulSet:
+		objectMeta = object.ObjectMeta
 	case *api_v1.Service:
 		objectMeta = object.ObjectMeta
 	case *api_v1.Pod:
@@ -102,6 +173,50 @@ func GetObjectMetaData(obj interface{}) (objectMeta meta_v1.ObjectMeta) {
 		objectMeta = object.ObjectMeta
 	case *events_v1.Event:
 		objectMeta = object.ObjectMeta
+	case *autoscaling_v1.HorizontalPodAutoscaler:
+		objectMeta = object.ObjectMeta
+	case *batch_v1.CronJob:
+		objectMeta = object.ObjectMeta
+	case *api_v1.PersistentVolumeClaim:
+		objectMeta = object.ObjectMeta
+	case *networking_v1.NetworkPolicy:
+		objectMeta = object.ObjectMeta
+	case *rbac_v1.Role:
+		objectMeta = object.ObjectMeta
+	case *rbac_v1.RoleBinding:
+		objectMeta = object.ObjectMeta
+	case *policy_v1.PodDisruptionBudget:
+		objectMeta = object.ObjectMeta
+	case *api_v1.ResourceQuota:
+		objectMeta = object.ObjectMeta
+	case *api_v1.LimitRange:
+		objectMeta = object.ObjectMeta
+	case *api_v1.Endpoints:
+		objectMeta = object.ObjectMeta
+	case *discovery_v1.EndpointSlice:
+		objectMeta = object.ObjectMeta
+	case *storage_v1.StorageClass:
+		objectMeta = object.ObjectMeta
+	case *scheduling_v1.PriorityClass:
+		objectMeta = object.ObjectMeta
+	case *admissionregistration_v1.MutatingWebhookConfiguration:
+		objectMeta = object.ObjectMeta
+	case *admissionregistration_v1.ValidatingWebhookConfiguration:
+		objectMeta = object.ObjectMeta
+	case *meta_v1.PartialObjectMetadata:
+		objectMeta = object.ObjectMeta
+	case *unstructured.Unstructured:
+		objectMeta = meta_v1.ObjectMeta{
+			Name:              object.GetName(),
+			Namespace:         object.GetNamespace(),
+			UID:               object.GetUID(),
+			Generation:        object.GetGeneration(),
+			CreationTimestamp: object.GetCreationTimestamp(),
+			DeletionTimestamp: object.GetDeletionTimestamp(),
+			Labels:            object.GetLabels(),
+			Annotations:       object.GetAnnotations(),
+			OwnerReferences:   object.GetOwnerReferences(),
+		}
 	}
 	return objectMeta
 }
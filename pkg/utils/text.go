@@ -0,0 +1,11 @@
+package utils
+
+import "unicode/utf8"
+
+// Truncate cuts the string to length characters ending with "...", without splitting the multi-byte characters
+func Truncate(s string, length int) string {
+	if utf8.RuneCountInString(s) <= length {
+		return s
+	}
+	return string([]rune(s)[:length-3]) + "..."
+}
@@ -0,0 +1,20 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "short", Truncate("short", 50))
+	assert.Equal(t, "abcd...", Truncate("abcdefghij", 7))
+
+	// the multi-byte characters aren't split
+	truncated := Truncate(strings.Repeat("é", 200), 130)
+	assert.True(t, utf8.ValidString(truncated))
+	assert.Equal(t, 130, utf8.RuneCountInString(truncated))
+	assert.Equal(t, strings.Repeat("é", 127)+"...", truncated)
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/marvasgit/kubestatewatch/config"
+)
+
+// TLSConfig returns the TLS configuration of the connections of a handler, nil when TLS is disabled
+func TLSConfig(c config.TLS) (*tls.Config, error) {
+	if !c.Enabled {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: c.InsecureSkipVerify}
+	if c.CaFile != "" {
+		caCert, err := os.ReadFile(c.CaFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no certificate found in %s", c.CaFile)
+		}
+	}
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}